@@ -2,39 +2,81 @@ package archive_extractor
 
 import (
 	"context"
+	"errors"
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/mholt/archives"
-	"os"
-	"strings"
+	"io"
 )
 
 type SevenZipArchiver struct {
 	MaxCompressRatio   int64
 	MaxNumberOfEntries int
+	EntryOptions
+	// Password decrypts the encrypted entries and headers, PasswordProvider is called once they turn out to be encrypted
+	// when it is empty
+	Password         string
+	PasswordProvider PasswordProvider
+	// MaxSpoolSize bounds the temporary file holding the content of the regular files until the symlinks are all known,
+	// see TarArchiver
+	MaxSpoolSize int64
 }
 
 func (sa SevenZipArchiver) ExtractArchive(path string,
 	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
-	ctx := context.Background()
-	maxBytesLimit, err := maxBytesLimit(path, sa.MaxCompressRatio)
-	if err != nil {
-		return err
-	}
-	provider := LimitAggregatingReadCloserProvider{
-		Limit: maxBytesLimit,
-	}
-	format := archives.SevenZip{}
-	archFile, err := os.Open(path)
+	return sa.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (sa SevenZipArchiver) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, archFile, err := openFileSource(path)
 	if err != nil {
 		return archiver_errors.NewOpenError(path, err)
 	}
 	defer func() {
 		_ = archFile.Close()
 	}()
+	return sa.ExtractSource(ctx, source, processingFunc, params)
+}
 
-	err = extract(ctx, format, archFile, sa.MaxNumberOfEntries, provider, processingFunc, params)
-	if err != nil && strings.Contains(err.Error(), archiver_errors.SevenZipDecodeError.Error()) {
-		return archiver_errors.NewOpenError(path, err)
+func (sa SevenZipArchiver) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := sa.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+func (sa SevenZipArchiver) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, sa.EntryOptions, FoldersSkip)
+	if err != nil {
+		return result, err
+	}
+	return result, result.finish(sa.extractEntries(ctx, source, processEntry))
+}
+
+func (sa SevenZipArchiver) extractEntries(ctx context.Context, source *Source, processEntry processEntryFunc) error {
+	maxBytesLimit, err := source.maxBytesLimit(sa.MaxCompressRatio)
+	if err != nil {
+		return err
+	}
+	section, cleanup, err := source.section()
+	if err != nil {
+		return archiver_errors.NewOpenError(source.Name, err)
+	}
+	defer cleanup()
+
+	password := newArchivePassword(source.Name, sa.Password, sa.PasswordProvider)
+	err = password.extract(processEntry, func(password string, processEntry processEntryFunc, stopAtEncrypted bool) error {
+		provider := &LimitAggregatingReadCloserProvider{
+			Limit: maxBytesLimit,
+		}
+		format := archives.SevenZip{Password: password}
+		links := newLinkExtraction(false, sa.MaxSpoolSize, sa.EntryOptions, provider, processEntry)
+		err := extract(ctx, format, io.NewSectionReader(section, 0, section.Size()), sa.MaxNumberOfEntries, provider, links.selects, links.processing(ctx, stopAtEncrypted))
+		return links.finish(ctx, err, func(selectEntry entrySelector, processEntry processEntryFunc) error {
+			return extract(ctx, format, io.NewSectionReader(section, 0, section.Size()), 0, provider, selectEntry, processEntry)
+		})
+	})
+	if err = classifyError(err); errors.Is(err, archiver_errors.ErrNotThisFormat) {
+		return archiver_errors.NewOpenError(source.Name, err)
 	}
 	return err
 }
@@ -0,0 +1,406 @@
+package archive_extractor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bodgit/sevenzip"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+)
+
+// ArchiveFS is a read only fs.FS over the entries of an archive, it can be used with fs.WalkDir, fs.Glob or http.FS.
+// Zip and 7z entries are opened on demand. The other formats are indexed by a first pass over the archive,
+// and every opened file is then reached by extracting the archive again until the file is found, so these archives
+// are better read sequentially (e.g. with fs.WalkDir). Folders which have no entry of their own are implied by the
+// paths of the entries, the empty folders of the formats whose archiver skips folders (e.g. tar) are not listed.
+// MaxCompressRatio applies to every opened file on its own.
+type ArchiveFS struct {
+	root          *fsNode
+	maxBytesLimit int64
+	closer        io.Closer
+}
+
+var (
+	_ fs.ReadDirFS = &ArchiveFS{}
+	_ fs.StatFS    = &ArchiveFS{}
+)
+
+// OpenFS identifies the format of the archive stored in path (see Identify) and returns a file system over its entries.
+// The archive stays open until the returned ArchiveFS is closed.
+func OpenFS(path string, options ...Option) (*ArchiveFS, error) {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return nil, err
+	}
+	config := newArchiverConfig(options)
+	maxBytesLimit, err := source.maxBytesLimit(config.MaxCompressRatio)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	identification, err := IdentifySource(source, options...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	afs := &ArchiveFS{
+		root:          &fsNode{name: ".", mode: fs.ModeDir | 0555, children: map[string]*fsNode{}},
+		maxBytesLimit: maxBytesLimit,
+		closer:        f,
+	}
+	switch identification.Format {
+	case FormatZip:
+		// the zip reader opens the archive by itself
+		f.Close()
+		afs.closer = nil
+		err = afs.indexZip(path, config)
+	case FormatSevenZip:
+		err = afs.indexSevenZip(source, config)
+	default:
+		err = afs.indexStream(identification.Archiver, source)
+	}
+	if err != nil {
+		afs.Close()
+		return nil, err
+	}
+	return afs, nil
+}
+
+func (afs *ArchiveFS) Open(name string) (fs.File, error) {
+	node, err := afs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.IsDir() {
+		return &archiveDir{node: node}, nil
+	}
+	return &archiveFile{node: node}, nil
+}
+
+func (afs *ArchiveFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := afs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return node.entries(), nil
+}
+
+func (afs *ArchiveFS) Stat(name string) (fs.FileInfo, error) {
+	return afs.lookup("stat", name)
+}
+
+// Close closes the archive, the files opened from the file system can't be read afterwards
+func (afs *ArchiveFS) Close() error {
+	if afs.closer == nil {
+		return nil
+	}
+	err := afs.closer.Close()
+	afs.closer = nil
+	return err
+}
+
+func (afs *ArchiveFS) lookup(op, name string) (*fsNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	node := afs.root
+	if name == "." {
+		return node, nil
+	}
+	for _, elem := range strings.Split(name, "/") {
+		node = node.children[elem]
+		if node == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+	}
+	return node, nil
+}
+
+func (afs *ArchiveFS) indexZip(path string, config *ArchiverConfig) error {
+	zr, err := openZipReader(path)
+	if err != nil {
+		return err
+	}
+	afs.closer = zr
+	if config.MaxNumberOfEntries > 0 && len(zr.File) > config.MaxNumberOfEntries {
+		return ErrTooManyEntries
+	}
+	password := newArchivePassword(path, config.Password, config.PasswordProvider)
+	for _, file := range zr.File {
+		afs.add(file.Name, file.FileInfo(), afs.limited(func() (io.ReadCloser, error) {
+			return openZipEntry(file, password)
+		}))
+	}
+	return nil
+}
+
+func (afs *ArchiveFS) indexSevenZip(source *Source, config *ArchiverConfig) error {
+	password, err := newArchivePassword(source.Name, config.Password, config.PasswordProvider).get()
+	if err != nil {
+		return err
+	}
+	r, err := sevenzip.NewReaderWithPassword(source.readerAt, source.Size, password)
+	if err != nil {
+		if err = encryptionError(err, password); errors.Is(err, ErrEncrypted) || errors.Is(err, ErrWrongPassword) {
+			return err
+		}
+		return archiver_errors.NewOpenError(source.Name, classifyError(err))
+	}
+	if config.MaxNumberOfEntries > 0 && len(r.File) > config.MaxNumberOfEntries {
+		return ErrTooManyEntries
+	}
+	for _, file := range r.File {
+		afs.add(file.Name, file.FileInfo(), afs.limited(file.Open))
+	}
+	return nil
+}
+
+// indexStream extracts the archive once to find its entries, the archiver limits apply to this extraction
+// and to the extractions done when a file is opened.
+func (afs *ArchiveFS) indexStream(archiver Archiver, source *Source) error {
+	entries := func() iter.Seq2[*ArchiveHeader, error] {
+		return iterate(func(processEntry processEntryFunc) error {
+			return extractSource(context.Background(), archiver, source, processEntry, map[string]interface{}{})
+		})
+	}
+	index := 0
+	for header, err := range entries() {
+		if err != nil {
+			return err
+		}
+		entryIndex := index
+		index++
+		// the formats which don't store the mode get read only permissions
+		perm := header.Mode &^ fs.ModeType
+		if header.Mode == 0 {
+			perm = 0444
+			if header.IsFolder {
+				perm = 0555
+			}
+		}
+		info := &fsNode{size: header.Size, modTime: time.Unix(header.ModTime, 0), mode: header.Type.modeType() | perm}
+		if header.IsFolder {
+			info.mode = fs.ModeDir | perm
+		}
+		afs.add(header.Name, info, func() (io.ReadCloser, error) {
+			return openStreamEntry(entries(), entryIndex)
+		})
+	}
+	return nil
+}
+
+// openStreamEntry extracts the archive until the entry at index, which is read while the extraction is paused
+func openStreamEntry(entries iter.Seq2[*ArchiveHeader, error], index int) (io.ReadCloser, error) {
+	next, stop := iter.Pull2(entries)
+	for i := 0; ; i++ {
+		header, err, ok := next()
+		if !ok {
+			stop()
+			return nil, fs.ErrNotExist
+		}
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		if i == index {
+			return &pulledEntryReader{Reader: header.ArchiveReader, stop: stop}, nil
+		}
+	}
+}
+
+type pulledEntryReader struct {
+	io.Reader
+	stop func()
+}
+
+func (per *pulledEntryReader) Close() error {
+	per.stop()
+	return nil
+}
+
+// limited applies the MaxCompressRatio limit to the files opened with open
+func (afs *ArchiveFS) limited(open func() (io.ReadCloser, error)) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		rc, err := open()
+		if err != nil {
+			return nil, err
+		}
+		provider := LimitAggregatingReadCloserProvider{Limit: afs.maxBytesLimit}
+		return provider.CreateLimitAggregatingReadCloser(rc), nil
+	}
+}
+
+// add puts an entry in the tree, creating its parent folders. The name is cleaned so it can't leave the root,
+// and a later entry replaces an earlier one with the same name, like when extracting the archive.
+func (afs *ArchiveFS) add(name string, info fs.FileInfo, open func() (io.ReadCloser, error)) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return
+	}
+	parent := afs.root
+	elems := strings.Split(name, "/")
+	for _, elem := range elems[:len(elems)-1] {
+		child := parent.children[elem]
+		if child == nil || !child.IsDir() {
+			child = &fsNode{name: elem, mode: fs.ModeDir | 0555, modTime: info.ModTime(), children: map[string]*fsNode{}}
+			parent.children[elem] = child
+		}
+		parent = child
+	}
+	elem := elems[len(elems)-1]
+	node := &fsNode{name: elem, size: info.Size(), mode: info.Mode(), modTime: info.ModTime(), open: open}
+	if info.IsDir() {
+		node.size = 0
+		node.open = nil
+		node.children = map[string]*fsNode{}
+		if existing := parent.children[elem]; existing != nil && existing.IsDir() {
+			node.children = existing.children
+		}
+	}
+	parent.children[elem] = node
+}
+
+// fsNode is a file or a folder of an ArchiveFS, it is its own fs.FileInfo and fs.DirEntry
+type fsNode struct {
+	name     string
+	size     int64
+	mode     fs.FileMode
+	modTime  time.Time
+	children map[string]*fsNode
+	open     func() (io.ReadCloser, error)
+}
+
+func (n *fsNode) Name() string               { return n.name }
+func (n *fsNode) Size() int64                { return n.size }
+func (n *fsNode) Mode() fs.FileMode          { return n.mode }
+func (n *fsNode) ModTime() time.Time         { return n.modTime }
+func (n *fsNode) IsDir() bool                { return n.mode.IsDir() }
+func (n *fsNode) Sys() any                   { return nil }
+func (n *fsNode) Type() fs.FileMode          { return n.mode.Type() }
+func (n *fsNode) Info() (fs.FileInfo, error) { return n, nil }
+
+// entries returns the children of a folder sorted by name
+func (n *fsNode) entries() []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		entries = append(entries, child)
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries
+}
+
+// archiveFile is opened lazily on the first read, seeking backwards opens it again
+type archiveFile struct {
+	node   *fsNode
+	reader io.ReadCloser
+	offset int64
+	closed bool
+}
+
+func (af *archiveFile) Stat() (fs.FileInfo, error) {
+	return af.node, nil
+}
+
+func (af *archiveFile) Read(p []byte) (int, error) {
+	if af.closed {
+		return 0, &fs.PathError{Op: "read", Path: af.node.name, Err: fs.ErrClosed}
+	}
+	if af.reader == nil {
+		reader, err := af.node.open()
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: af.node.name, Err: err}
+		}
+		af.reader = reader
+	}
+	n, err := af.reader.Read(p)
+	af.offset += int64(n)
+	return n, err
+}
+
+func (af *archiveFile) Seek(offset int64, whence int) (int64, error) {
+	if af.closed {
+		return 0, &fs.PathError{Op: "seek", Path: af.node.name, Err: fs.ErrClosed}
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += af.offset
+	case io.SeekEnd:
+		offset += af.node.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: af.node.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: af.node.name, Err: fs.ErrInvalid}
+	}
+	if offset < af.offset && af.reader != nil {
+		af.reader.Close()
+		af.reader = nil
+		af.offset = 0
+	}
+	if _, err := io.CopyN(io.Discard, af, offset-af.offset); err != nil && err != io.EOF {
+		return 0, err
+	}
+	// seeking past the end is allowed, the next read returns io.EOF
+	af.offset = offset
+	return offset, nil
+}
+
+func (af *archiveFile) Close() error {
+	if af.closed {
+		return &fs.PathError{Op: "close", Path: af.node.name, Err: fs.ErrClosed}
+	}
+	af.closed = true
+	if af.reader != nil {
+		return af.reader.Close()
+	}
+	return nil
+}
+
+type archiveDir struct {
+	node    *fsNode
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (ad *archiveDir) Stat() (fs.FileInfo, error) {
+	return ad.node, nil
+}
+
+func (ad *archiveDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ad.node.name, Err: errors.New("is a directory")}
+}
+
+func (ad *archiveDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if ad.entries == nil {
+		ad.entries = ad.node.entries()
+	}
+	remaining := ad.entries[ad.offset:]
+	if count <= 0 {
+		ad.offset = len(ad.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	count = min(count, len(remaining))
+	ad.offset += count
+	return remaining[:count], nil
+}
+
+func (ad *archiveDir) Close() error {
+	return nil
+}
@@ -0,0 +1,179 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveFS(t *testing.T) {
+	tests := []struct {
+		path  string
+		files []string
+	}{
+		{"./fixtures/testwithcontent.zip", []string{"test.txt"}},
+		{"./fixtures/testwithcontent.7z", []string{"compression.go"}},
+		{"./fixtures/test.tar.gz", []string{"logRotator-1.0/README.md", "logRotator-1.0/log_rotator.go"}},
+		{"./fixtures/test.deb", []string{"control.tar.gz", "data.tar.xz", "debian-binary"}},
+	}
+	for _, test := range tests {
+		t.Run(filepath.Base(test.path), func(t *testing.T) {
+			afs, err := OpenFS(test.path)
+			require.NoError(t, err)
+			defer afs.Close()
+			assert.NoError(t, fstest.TestFS(afs, test.files...))
+		})
+	}
+}
+
+func TestArchiveFSWalkDir(t *testing.T) {
+	afs, err := OpenFS("./fixtures/testwithmanyfiles.zip")
+	require.NoError(t, err)
+	defer afs.Close()
+	count := 0
+	err = fs.WalkDir(afs, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			count++
+		}
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 100, count)
+}
+
+func TestArchiveFSContent(t *testing.T) {
+	path := tarGzFile(t, "content.tar.gz",
+		testEntry{name: "a/b/first.txt", content: []byte("first")},
+		testEntry{name: "./a/second.txt", content: []byte("second")},
+		testEntry{name: "../../escape.txt", content: []byte("escape")})
+	afs, err := OpenFS(path)
+	require.NoError(t, err)
+	defer afs.Close()
+
+	content, err := fs.ReadFile(afs, "a/second.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(content))
+	content, err = fs.ReadFile(afs, "a/b/first.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(content))
+	// names leaving the archive are kept inside its root
+	content, err = fs.ReadFile(afs, "escape.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "escape", string(content))
+
+	matches, err := fs.Glob(afs, "a/*.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/second.txt"}, matches)
+
+	info, err := afs.Stat("a/b")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	_, err = afs.Stat("a/missing.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = afs.Open("../escape.txt")
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestArchiveFSSeek(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seek.zip")
+	require.NoError(t, os.WriteFile(path, zipBytes(t, testEntry{name: "seek.txt", content: []byte("0123456789")}), 0644))
+	afs, err := OpenFS(path)
+	require.NoError(t, err)
+	defer afs.Close()
+	f, err := afs.Open("seek.txt")
+	require.NoError(t, err)
+	defer f.Close()
+	seeker := f.(io.ReadSeeker)
+
+	_, err = seeker.Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(seeker)
+	require.NoError(t, err)
+	assert.Equal(t, "6789", string(rest))
+
+	_, err = seeker.Seek(-8, io.SeekEnd)
+	require.NoError(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(seeker, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "234", string(buf))
+
+	_, err = seeker.Seek(0, 3)
+	var pathErr *fs.PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.ErrorIs(t, err, fs.ErrInvalid)
+	assert.Equal(t, "seek", pathErr.Op)
+}
+
+func TestArchiveFSModes(t *testing.T) {
+	path := tarGzFile(t, "modes.tar.gz",
+		testEntry{name: "bin/tool", content: []byte("tool"), mode: 0755},
+		testEntry{name: "share/", mode: fs.ModeDir | 0700},
+		fileEntry("share/readme.txt", "read me"),
+		symlinkEntry("tool", "bin/tool"))
+	afs, err := OpenFS(path, WithFolders(FoldersExplicit))
+	require.NoError(t, err)
+	defer afs.Close()
+	modes := map[string]fs.FileMode{}
+	for _, name := range []string{"bin/tool", "share", "share/readme.txt", "tool"} {
+		info, err := afs.Stat(name)
+		require.NoError(t, err)
+		modes[name] = info.Mode()
+	}
+	assert.Equal(t, map[string]fs.FileMode{
+		"bin/tool":         0755,
+		"share":            fs.ModeDir | 0700,
+		"share/readme.txt": 0644,
+		"tool":             fs.ModeSymlink | 0777,
+	}, modes)
+}
+
+func TestArchiveFSRatioLimit(t *testing.T) {
+	afs, err := OpenFS("./fixtures/testwithsinglelargefile.zip", WithMaxCompressRatio(1))
+	require.NoError(t, err)
+	defer afs.Close()
+	entries, err := afs.ReadDir(".")
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	f, err := afs.Open(entries[0].Name())
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = io.Copy(io.Discard, f)
+	assert.True(t, IsErrCompressLimitReached(err))
+}
+
+func TestArchiveFSTooManyEntries(t *testing.T) {
+	_, err := OpenFS("./fixtures/testwithmanyfiles.zip", WithMaxNumberOfEntries(10))
+	assert.ErrorIs(t, err, ErrTooManyEntries)
+	_, err = OpenFS("./fixtures/testmanylarge.tar.gz", WithMaxNumberOfEntries(1))
+	assert.ErrorIs(t, err, ErrTooManyEntries)
+}
+
+func TestArchiveFSReadsEntryOfStream(t *testing.T) {
+	path := tarGzFile(t, "many.tar.gz",
+		testEntry{name: "1.txt", content: bytes.Repeat([]byte("1"), 100)},
+		testEntry{name: "2.txt", content: bytes.Repeat([]byte("2"), 100)},
+		testEntry{name: "3.txt", content: bytes.Repeat([]byte("3"), 100)})
+	afs, err := OpenFS(path)
+	require.NoError(t, err)
+	defer afs.Close()
+	// files are read out of order and while other files are open
+	third, err := afs.Open("3.txt")
+	require.NoError(t, err)
+	defer third.Close()
+	first, err := fs.ReadFile(afs, "1.txt")
+	require.NoError(t, err)
+	content, err := io.ReadAll(third)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte("1"), 100), first)
+	assert.Equal(t, bytes.Repeat([]byte("3"), 100), content)
+}
@@ -1,36 +1,130 @@
 package archive_extractor
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/jfrog/go-archive-extractor/utils"
 	"io"
-	"os"
+	"io/fs"
 )
 
 type Archiver interface {
 	ExtractArchive(path string, processingFunc func(header *ArchiveHeader, params map[string]interface{}) error, params map[string]interface{}) error
+	// ExtractArchiveContext behaves like ExtractArchive, but stops and returns ctx.Err() once ctx is done
+	ExtractArchiveContext(ctx context.Context, path string, processingFunc func(header *ArchiveHeader, params map[string]interface{}) error, params map[string]interface{}) error
+}
+
+var (
+	// SkipEntry is returned by processingFunc when it is done with the entry, the extraction goes on with the next entries.
+	// The entry is not counted in ExtractResult, and the rest of its content is not read to compute its digests.
+	SkipEntry = errors.New("skip this entry")
+	// StopExtraction is returned by processingFunc to stop the extraction, which then returns nil
+	StopExtraction = errors.New("stop the extraction")
+)
+
+// EntryOptions are the options every archiver applies to the entries of the archive, they are embedded in the archivers
+// and in ArchiverConfig
+type EntryOptions struct {
+	// MaxEntrySize limits the uncompressed size of every entry, and MaxEntryCompressRatio its uncompressed size
+	// over the compressed size stored in the archive when the format stores it, see ErrEntryLimitReached
+	MaxEntrySize          int64
+	MaxEntryCompressRatio int64
+	// Digests are computed for every entry, see ArchiveHeader.Digests
+	Digests []DigestAlgorithm
+	// Filter selects the entries passed to processingFunc, see EntryFilter
+	Filter EntryFilter
+	// Folders controls whether the folders are passed to processingFunc, see FolderReporting
+	Folders FolderReporting
+	// Names controls how the names of the entries are normalised, see NameNormalization
+	Names NameNormalization
 }
 
 type ArchiveHeader struct {
 	ArchiveReader io.Reader
 	IsFolder      bool
 	Name          string
-	ModTime       int64
-	Size          int64
+	// RawName is the name as stored in the archive, Name is normalised from it (see NameNormalization)
+	RawName string
+	// Path is the virtual path of the entry, when extracting nested archives it is prefixed by the path
+	// of the archives containing it (e.g. outer.tar.gz!/lib/a.war!/WEB-INF/lib/b.jar!/x.class), otherwise it equals Name
+	Path    string
+	ModTime int64
+	Size    int64
+	// CompressedSize is the size of the entry content stored in the archive, 0 when the format doesn't store it
+	CompressedSize int64
+	// Mode holds the permission and type bits of the entry, it is 0 when the format doesn't store them
+	Mode fs.FileMode
+	// Type is EntryRegular when the format doesn't store the type of its entries
+	Type EntryType
+	// LinkTarget is the target of a symlink or of a hardlink
+	LinkTarget string
+	// Aliases are the paths of all the hardlinks to LinkTarget, Name being the first of them,
+	// when TarArchiver.HardlinkAliases reports the hardlinks to a file as a single entry
+	Aliases []string
+	Uid     int
+	Gid     int
+	Uname   string
+	Gname   string
+	// ModTimeNs, AccessTimeNs and ChangeTimeNs are Unix times in nanoseconds, 0 when the format doesn't store them
+	ModTimeNs    int64
+	AccessTimeNs int64
+	ChangeTimeNs int64
+	// Digests are the hex encoded digests requested from the archiver, set once the content is read to its end.
+	// The archiver reads the part of the content that processingFunc didn't read once it returns.
+	Digests map[DigestAlgorithm]string
+	// Encrypted tells the content of the entry is encrypted, reading it without the right password
+	// returns ErrEncrypted or ErrWrongPassword. 7z entries are only known to be encrypted once reading them fails.
+	Encrypted bool
+	// OutsideRoot tells the target of the symlink is outside of the archive root. TarArchiver reports such symlinks as is,
+	// like the symlinks to folders, while the symlinks to files are reported as the content of their target under the path of the symlink.
+	OutsideRoot bool
+	// ViaSymlink is the symlink to a folder the path of the entry goes through, when the entry is reported under a path
+	// which is not stored in the archive (e.g. lib/libfoo.so for usr/lib/libfoo.so when lib links to usr/lib)
+	ViaSymlink string
+	// selected is set when the entry was matched against the filter before being opened
+	selected bool
 }
 
-func NewArchiveHeader(archiveReader io.Reader, name string, modTime int64, size int64) *ArchiveHeader {
-	return &ArchiveHeader{ArchiveReader: archiveReader, IsFolder: utils.IsFolder(name), Name: name, ModTime: modTime, Size: size}
-}
+type EntryType int
 
-func maxBytesLimit(path string, maxCompressRation int64) (int64, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close() // close the file without handling a possible error
-	fi, err := f.Stat()
-	if err != nil {
-		return 0, err
+const (
+	EntryRegular EntryType = iota
+	EntryDir
+	EntrySymlink
+	EntryHardlink
+	EntryCharDevice
+	EntryBlockDevice
+	EntryFifo
+	EntrySocket
+)
+
+func (et EntryType) String() string {
+	switch et {
+	case EntryRegular:
+		return "regular"
+	case EntryDir:
+		return "dir"
+	case EntrySymlink:
+		return "symlink"
+	case EntryHardlink:
+		return "hardlink"
+	case EntryCharDevice:
+		return "char device"
+	case EntryBlockDevice:
+		return "block device"
+	case EntryFifo:
+		return "fifo"
+	case EntrySocket:
+		return "socket"
 	}
-	return fi.Size() * maxCompressRation, nil
+	return fmt.Sprintf("EntryType(%d)", int(et))
+}
+
+func (et EntryType) MarshalText() ([]byte, error) {
+	return []byte(et.String()), nil
+}
+
+func NewArchiveHeader(archiveReader io.Reader, name string, modTime int64, size int64) *ArchiveHeader {
+	return &ArchiveHeader{ArchiveReader: archiveReader, IsFolder: utils.IsFolder(name), Name: name, RawName: name, Path: name, ModTime: modTime, Size: size}
 }
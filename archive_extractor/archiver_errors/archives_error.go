@@ -2,6 +2,7 @@ package archiver_errors
 
 import "fmt"
 
+// Deprecated: the errors of the libraries are not matched by their message anymore, use errors.Is with ErrNotThisFormat
 var (
 	RarDecodeError      = fmt.Errorf("rardecode: RAR signature not found")
 	SevenZipDecodeError = fmt.Errorf("sevenzip: not a valid 7-zip file")
@@ -0,0 +1,74 @@
+package archiver_errors
+
+import "errors"
+
+// The kinds of the errors returned by the archivers, to be tested with errors.Is.
+// The errors keep their own type and message, the kind is added to the errors they wrap.
+var (
+	// ErrNotThisFormat means the file isn't an archive of the format of the archiver
+	ErrNotThisFormat = errors.New("not an archive of this format")
+	// ErrCorrupt means the archive, or one of its entries, is malformed or fails its checksum
+	ErrCorrupt = errors.New("corrupt archive")
+	// ErrTruncated means the archive, or one of its entries, ends unexpectedly
+	ErrTruncated = errors.New("truncated archive")
+	// ErrUnsupportedMethod means an entry is compressed or encrypted with a method that isn't supported
+	ErrUnsupportedMethod = errors.New("unsupported compression method")
+	// ErrEncrypted means an entry can't be decrypted, either the password is missing or it is wrong
+	ErrEncrypted = errors.New("archive entry is encrypted, a password is required")
+	// ErrLimitExceeded means the extraction stopped at one of the limits of the archiver, e.g. MaxCompressRatio
+	ErrLimitExceeded = errors.New("archive limit exceeded")
+	// ErrCallback means the processing function returned an error, see CallbackError
+	ErrCallback = errors.New("processing function error")
+)
+
+var kinds = []error{ErrNotThisFormat, ErrCorrupt, ErrTruncated, ErrUnsupportedMethod, ErrEncrypted, ErrLimitExceeded, ErrCallback}
+
+// KindOf returns the kind of err, nil when it has none
+func KindOf(err error) error {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// KindError adds a kind to an error, its message is the one of the error
+type KindError struct {
+	Kind error
+	Err  error
+}
+
+// WithKind returns err with the given kind, err is returned as is when it is nil or already has a kind
+func WithKind(kind, err error) error {
+	if err == nil || KindOf(err) != nil {
+		return err
+	}
+	return &KindError{Kind: kind, Err: err}
+}
+
+func (ke *KindError) Error() string {
+	return ke.Err.Error()
+}
+
+func (ke *KindError) Unwrap() []error {
+	return []error{ke.Err, ke.Kind}
+}
+
+// CallbackError is an error returned by the processing function, its message is the one of the error.
+// The errors which already have a kind, e.g. the errors reading the entry the processing function returns, aren't wrapped.
+type CallbackError struct {
+	Err error
+}
+
+func (ce *CallbackError) Error() string {
+	return ce.Err.Error()
+}
+
+func (ce *CallbackError) Unwrap() error {
+	return ce.Err
+}
+
+func (ce *CallbackError) Is(target error) bool {
+	return target == ErrCallback
+}
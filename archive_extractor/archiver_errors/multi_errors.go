@@ -37,3 +37,11 @@ func (m *MultiError) Error() string {
 	}
 	return buf.String()
 }
+
+// Unwrap returns the errors, so that errors.Is and errors.As match any of them
+func (m *MultiError) Unwrap() []error {
+	if m == nil {
+		return nil
+	}
+	return m.Errors
+}
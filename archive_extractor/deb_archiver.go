@@ -1,11 +1,11 @@
 package archive_extractor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/blakesmith/ar"
 	"io"
-	"os"
 
 	"github.com/jfrog/go-archive-extractor/utils"
 )
@@ -13,31 +13,65 @@ import (
 type DebArchiver struct {
 	MaxCompressRatio   int64
 	MaxNumberOfEntries int
+	EntryOptions
+	// SkipFoldersCheck reports the entries whose name looks like a folder, which are skipped by default.
+	// It makes FoldersDefault mean FoldersExplicit, see Folders.
+	SkipFoldersCheck bool
 }
 
+// DebArchiverSkipFoldersCheckParamsKey sets SkipFoldersCheck from the params of the map based API
 const DebArchiverSkipFoldersCheckParamsKey = "DebArchiverSkipFoldersCheckParamsKey"
 
 func (da DebArchiver) ExtractArchive(path string,
 	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
-	maxBytesLimit, err := maxBytesLimit(path, da.MaxCompressRatio)
+	return da.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (da DebArchiver) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, debFile, err := openFileSource(path)
 	if err != nil {
 		return err
 	}
-	provider := LimitAggregatingReadCloserProvider{
-		Limit: maxBytesLimit,
+	defer debFile.Close()
+	return da.ExtractSource(ctx, source, processingFunc, params)
+}
+
+func (da DebArchiver) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	if skipFolderCheck(params) {
+		da.SkipFoldersCheck = true
 	}
-	debFile, err := os.Open(path)
+	_, err := da.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+func (da DebArchiver) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, da.EntryOptions, da.defaultFolders())
+	if err != nil {
+		return result, err
+	}
+	return result, result.finish(da.extractEntries(ctx, source, processEntry))
+}
+
+func (da DebArchiver) extractEntries(ctx context.Context, source *Source, processEntry processEntryFunc) error {
+	maxBytesLimit, err := source.maxBytesLimit(da.MaxCompressRatio)
 	if err != nil {
 		return err
 	}
-	defer debFile.Close()
-	rc := ar.NewReader(debFile)
+	provider := LimitAggregatingReadCloserProvider{
+		Limit: maxBytesLimit,
+	}
+	rc := ar.NewReader(source.stream())
 	if rc == nil {
-		return errors.New(fmt.Sprintf("Failed to open deb file : %s", path))
+		return errors.New(fmt.Sprintf("Failed to open deb file : %s", source.Name))
 	}
 
 	entriesCount := 0
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if da.MaxNumberOfEntries != 0 && entriesCount > da.MaxNumberOfEntries {
 			return ErrTooManyEntries
 		}
@@ -50,20 +84,29 @@ func (da DebArchiver) ExtractArchive(path string,
 			return err
 		}
 		if archiveEntry == nil {
-			return errors.New(fmt.Sprintf("Failed to open file : %s", path))
+			return errors.New(fmt.Sprintf("Failed to open file : %s", source.Name))
 		}
-		if skipFolderCheck(params) || !utils.IsFolder(archiveEntry.Name) {
-			limitingReader := provider.CreateLimitAggregatingReadCloser(rc)
-			archiveHeader := NewArchiveHeader(limitingReader, archiveEntry.Name, archiveEntry.ModTime.Unix(), archiveEntry.Size)
-			err = processingFunc(archiveHeader, params)
-			if err != nil {
-				return err
-			}
+		limitingReader := provider.CreateLimitAggregatingReadCloser(utils.NewContextReader(ctx, rc))
+		archiveHeader := NewArchiveHeader(limitingReader, archiveEntry.Name, archiveEntry.ModTime.Unix(), archiveEntry.Size)
+		archiveHeader.setMode(unixModeToFileMode(archiveEntry.Mode))
+		archiveHeader.Uid = archiveEntry.Uid
+		archiveHeader.Gid = archiveEntry.Gid
+		archiveHeader.ModTimeNs = unixNano(archiveEntry.ModTime)
+		err = processEntry(archiveHeader)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+func (da DebArchiver) defaultFolders() FolderReporting {
+	if da.SkipFoldersCheck {
+		return FoldersExplicit
+	}
+	return FoldersSkip
+}
+
 func skipFolderCheck(params map[string]interface{}) bool {
 	value, found := params[DebArchiverSkipFoldersCheckParamsKey]
 	if !found {
@@ -3,7 +3,11 @@
 package archive_extractor
 
 import (
+	"context"
 	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -79,3 +83,44 @@ func TestDebArchiverSkipFoldersCheck(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 3, len(entries))
 }
+
+func TestDebArchiverSkipFoldersCheckField(t *testing.T) {
+	var entries []string
+	processor := func(header *ArchiveHeader) error {
+		entries = append(entries, header.Name)
+		return nil
+	}
+	f, err := os.Open("./fixtures/testslashesinentrynames.deb")
+	require.NoError(t, err)
+	defer f.Close()
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	source := NewReaderAtSource("testslashesinentrynames.deb", f, fi.Size())
+	result, err := DebArchiver{SkipFoldersCheck: true}.ExtractWithResult(context.Background(), source, processor)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, 3, result.EntriesCount)
+}
+
+func TestDebArchiverContextCanceled(t *testing.T) {
+	za := &DebArchiver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := za.ExtractArchiveContext(ctx, "./fixtures/test.deb", processingReadingFunc, params())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDebArchiverExtractSourceStream(t *testing.T) {
+	f, err := os.Open("./fixtures/test.deb")
+	require.NoError(t, err)
+	defer f.Close()
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	za := &DebArchiver{MaxCompressRatio: 1}
+	funcParams := params()
+	err = za.ExtractSource(context.Background(), NewReaderSource("test.deb", io.MultiReader(f), fi.Size()), processingFunc, funcParams)
+	require.NoError(t, err)
+	ad := funcParams["archiveData"].(*ArchiveData)
+	assert.Equal(t, "data.tar.xz", ad.Name)
+	assert.Equal(t, int64(42284), ad.Size)
+}
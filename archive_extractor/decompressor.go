@@ -1,16 +1,18 @@
 package archive_extractor
 
 import (
+	"context"
 	"fmt"
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/jfrog/go-archive-extractor/compression"
-	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Decompressor struct {
 	MaxCompressRatio int64
+	EntryOptions
 }
 
 const (
@@ -19,36 +21,64 @@ const (
 
 func (dc Decompressor) ExtractArchive(path string,
 	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
-	maxBytesLimit, err := maxBytesLimit(path, dc.MaxCompressRatio)
+	return dc.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (dc Decompressor) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return archiver_errors.New(err)
+	}
+	defer f.Close()
+	return dc.ExtractSource(ctx, source, processingFunc, params)
+}
+
+func (dc Decompressor) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := dc.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+func (dc Decompressor) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, dc.EntryOptions, FoldersSkip)
+	if err != nil {
+		return result, err
+	}
+	return result, result.finish(dc.extractEntries(ctx, source, processEntry))
+}
+
+func (dc Decompressor) extractEntries(ctx context.Context, source *Source, processEntry processEntryFunc) error {
+	maxBytesLimit, err := source.maxBytesLimit(dc.MaxCompressRatio)
 	if err != nil {
 		return archiver_errors.New(err)
 	}
 	provider := LimitAggregatingReadCloserProvider{
 		Limit: maxBytesLimit,
 	}
-	cReader, isCompressed, err := compression.NewReader(path)
+	cReader, isCompressed, err := compression.NewReaderFrom(source.stream(), source.Name, compression.WithContext(ctx))
 	if err != nil {
-		return archiver_errors.New(err)
+		return archiver_errors.New(classifyError(err))
 	}
 	defer cReader.Close()
 	if !isCompressed {
-		return archiver_errors.New(fmt.Errorf(NotCompressedOrNotSupportedError, path))
+		return archiver_errors.New(archiver_errors.WithKind(archiver_errors.ErrNotThisFormat, fmt.Errorf(NotCompressedOrNotSupportedError, source.Name)))
 	}
 	limitingReader := provider.CreateLimitAggregatingReadCloser(cReader)
 	defer limitingReader.Close()
-	f, err := os.Open(path)
-	if err != nil {
-		return archiver_errors.New(err)
-	}
-	defer f.Close()
-	fInfo, err := f.Stat()
-	if err != nil {
-		return archiver_errors.New(err)
+	modTime := source.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now()
 	}
 	// removing the compression extension since now we have a decompressed file
-	name := strings.TrimSuffix(fInfo.Name(), filepath.Ext(fInfo.Name()))
-	archiveHeader := NewArchiveHeader(limitingReader, name, fInfo.ModTime().Unix(), fInfo.Size())
-	err = processingFunc(archiveHeader, params)
+	baseName := filepath.Base(source.Name)
+	name := strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	archiveHeader := NewArchiveHeader(limitingReader, name, modTime.Unix(), source.Size)
+	archiveHeader.ModTimeNs = unixNano(source.ModTime)
+	if source.Size > 0 {
+		archiveHeader.CompressedSize = source.Size
+	}
+	err = processEntry(archiveHeader)
 	if err != nil {
 		return err
 	}
@@ -3,10 +3,13 @@
 package archive_extractor
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
 	"testing"
 	"time"
 )
@@ -105,3 +108,16 @@ func TestExtractArchive_MaxRatioNotReached(t *testing.T) {
 	err := dc.ExtractArchive("./fixtures/testsinglelarge.txt.xz", processingReadingFunc, funcParams)
 	assert.NoError(t, err)
 }
+
+func TestDecompressor_ExtractSource(t *testing.T) {
+	content, err := os.ReadFile("./fixtures/test.txt.bz2")
+	require.NoError(t, err)
+	dc := &Decompressor{MaxCompressRatio: 100}
+	funcParams := params()
+	source := NewReaderSource("uploads/test.txt.bz2", bytes.NewReader(content), int64(len(content)))
+	err = dc.ExtractSource(context.Background(), source, processingFunc, funcParams)
+	require.NoError(t, err)
+	ad := funcParams["archiveData"].(*ArchiveData)
+	assert.Equal(t, "test.txt", ad.Name)
+	assert.Equal(t, int64(len(content)), ad.Size)
+}
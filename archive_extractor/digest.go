@@ -0,0 +1,90 @@
+package archive_extractor
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// DigestAlgorithm names a hash computed over the content of the entries
+type DigestAlgorithm string
+
+const (
+	DigestMD5    DigestAlgorithm = "md5"
+	DigestSHA1   DigestAlgorithm = "sha1"
+	DigestSHA256 DigestAlgorithm = "sha256"
+	DigestSHA512 DigestAlgorithm = "sha512"
+)
+
+var digestFactories = map[DigestAlgorithm]func() hash.Hash{
+	DigestMD5:    md5.New,
+	DigestSHA1:   sha1.New,
+	DigestSHA256: sha256.New,
+	DigestSHA512: sha512.New,
+}
+
+// withDigests computes the digests of every entry while processEntry reads it.
+// The part of the entry processEntry didn't read is read once it returns, so the digests are always set.
+func withDigests(processEntry processEntryFunc, digests []DigestAlgorithm) (processEntryFunc, error) {
+	if len(digests) == 0 {
+		return processEntry, nil
+	}
+	for _, algorithm := range digests {
+		if _, ok := digestFactories[algorithm]; !ok {
+			return nil, fmt.Errorf("unsupported digest algorithm: %q", algorithm)
+		}
+	}
+	return func(header *ArchiveHeader) error {
+		reader := newDigestReader(header, digests)
+		header.ArchiveReader = reader
+		if err := processEntry(header); err != nil {
+			return err
+		}
+		if header.Digests == nil {
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// digestReader sets the digests of the header once its content is read to the end
+type digestReader struct {
+	reader io.Reader
+	header *ArchiveHeader
+	hashes map[DigestAlgorithm]hash.Hash
+	writer io.Writer
+}
+
+func newDigestReader(header *ArchiveHeader, digests []DigestAlgorithm) *digestReader {
+	hashes := make(map[DigestAlgorithm]hash.Hash, len(digests))
+	writers := make([]io.Writer, 0, len(digests))
+	for _, algorithm := range digests {
+		if _, ok := hashes[algorithm]; ok {
+			continue
+		}
+		h := digestFactories[algorithm]()
+		hashes[algorithm] = h
+		writers = append(writers, h)
+	}
+	return &digestReader{reader: header.ArchiveReader, header: header, hashes: hashes, writer: io.MultiWriter(writers...)}
+}
+
+func (dr *digestReader) Read(p []byte) (int, error) {
+	n, err := dr.reader.Read(p)
+	dr.writer.Write(p[:n])
+	if err == io.EOF && dr.header.Digests == nil {
+		digests := make(map[DigestAlgorithm]string, len(dr.hashes))
+		for algorithm, h := range dr.hashes {
+			digests[algorithm] = hex.EncodeToString(h.Sum(nil))
+		}
+		dr.header.Digests = digests
+	}
+	return n, err
+}
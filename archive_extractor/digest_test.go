@@ -0,0 +1,98 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hexSha256(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func hexMd5(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDigestsFullyRead(t *testing.T) {
+	content, err := os.ReadFile("./fixtures/test.txt")
+	require.NoError(t, err)
+	za := ZipArchiver{EntryOptions: EntryOptions{Digests: []DigestAlgorithm{DigestSHA256, DigestMD5}}}
+	data := zipBytes(t, testEntry{name: "test.txt", content: content})
+	source := NewReaderAtSource("test.zip", bytes.NewReader(data), int64(len(data)))
+	var digests map[DigestAlgorithm]string
+	_, err = za.ExtractWithResult(context.Background(), source, func(header *ArchiveHeader) error {
+		read, err := io.ReadAll(header.ArchiveReader)
+		require.NoError(t, err)
+		assert.Equal(t, content, read)
+		// the digests are available as soon as the content is read
+		digests = header.Digests
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[DigestAlgorithm]string{DigestSHA256: hexSha256(content), DigestMD5: hexMd5(content)}, digests)
+}
+
+func TestDigestsPartiallyRead(t *testing.T) {
+	path := tarGzFile(t, "partial.tar.gz", testEntry{name: "a.txt", content: []byte("first content")}, testEntry{name: "b.txt", content: []byte("second content")})
+	var headers []*ArchiveHeader
+	result, err := ExtractTyped(context.Background(), TarArchiver{EntryOptions: EntryOptions{Digests: []DigestAlgorithm{DigestSHA256}}}, path,
+		func(header *ArchiveHeader, headers *[]*ArchiveHeader) error {
+			buf := make([]byte, 3)
+			_, err := io.ReadFull(header.ArchiveReader, buf)
+			*headers = append(*headers, header)
+			return err
+		}, &headers)
+	require.NoError(t, err)
+	require.Len(t, headers, 2)
+	assert.Equal(t, hexSha256([]byte("first content")), headers[0].Digests[DigestSHA256])
+	assert.Equal(t, hexSha256([]byte("second content")), headers[1].Digests[DigestSHA256])
+	assert.Equal(t, int64(len("first content")+len("second content")), result.BytesRead, "the remaining content is read")
+}
+
+func TestDigestsNotRequested(t *testing.T) {
+	for header, err := range (ZipArchiver{}).Entries(context.Background(), "./fixtures/testwithcontent.zip") {
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, header.ArchiveReader)
+		require.NoError(t, err)
+		assert.Nil(t, header.Digests)
+	}
+}
+
+func TestDigestsUnsupportedAlgorithm(t *testing.T) {
+	err := ZipArchiver{EntryOptions: EntryOptions{Digests: []DigestAlgorithm{"crc"}}}.ExtractArchive("./fixtures/testwithcontent.zip", processingFunc, params())
+	assert.ErrorContains(t, err, "unsupported digest algorithm")
+}
+
+func TestDigestsRecursive(t *testing.T) {
+	var classHeader *ArchiveHeader
+	re := RecursiveExtractor{MaxDepth: DefaultMaxDepth, EntryOptions: EntryOptions{Digests: []DigestAlgorithm{DigestSHA256}}}
+	err := re.ExtractArchive(nestedArchive(t), func(header *ArchiveHeader, params map[string]interface{}) error {
+		if filepath.Base(header.Name) == "x.class" {
+			classHeader = header
+		}
+		return nil
+	}, params())
+	require.NoError(t, err)
+	require.NotNil(t, classHeader)
+	assert.Equal(t, hexSha256([]byte("class content")), classHeader.Digests[DigestSHA256])
+}
+
+func TestWithDigestsOption(t *testing.T) {
+	identification, err := Identify("./fixtures/test.deb", WithDigests(DigestSHA1))
+	require.NoError(t, err)
+	assert.Equal(t, []DigestAlgorithm{DigestSHA1}, identification.Archiver.(DebArchiver).Digests)
+}
@@ -0,0 +1,175 @@
+package archive_extractor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/mholt/archives"
+	"github.com/nwaples/rardecode/v2"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+)
+
+var (
+	// ErrEncrypted is returned when reading an encrypted entry, or opening an archive with encrypted headers, without a password
+	ErrEncrypted = archiver_errors.ErrEncrypted
+	// ErrWrongPassword is returned when the password doesn't decrypt the entry, it matches ErrEncrypted too
+	ErrWrongPassword = archiver_errors.WithKind(archiver_errors.ErrEncrypted, errors.New("wrong password for the encrypted archive entry"))
+)
+
+// PasswordProvider returns the password of the archive named archiveName, or an empty string if it is unknown.
+// It is called at most once per extraction, when the archiver has no Password, and only for the encrypted archives:
+// zip archives call it for their first encrypted entry, 7z and rar archives once their headers or an entry they open
+// turn out to be encrypted. 7z and rar archives are then read again with the password, without passing the entries
+// already processed to the processing function again. OpenFS calls it before indexing 7z archives.
+type PasswordProvider func(archiveName string) (string, error)
+
+// archivePassword resolves the password of an archive once it is needed
+type archivePassword struct {
+	archiveName string
+	password    string
+	provider    PasswordProvider
+	err         error
+	mutex       sync.Mutex
+}
+
+func newArchivePassword(archiveName, password string, provider PasswordProvider) *archivePassword {
+	return &archivePassword{archiveName: archiveName, password: password, provider: provider}
+}
+
+func (ap *archivePassword) get() (string, error) {
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+	if ap.password == "" && ap.provider != nil && ap.err == nil {
+		ap.password, ap.err = ap.provider(ap.archiveName)
+		ap.provider = nil
+	}
+	return ap.password, ap.err
+}
+
+// pending tells the provider is still to be called for the password
+func (ap *archivePassword) pending() bool {
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+	return ap.password == "" && ap.provider != nil && ap.err == nil
+}
+
+// extract reads a 7z or rar archive with extractPass, which opens the archive with password. While the provider is pending,
+// extractPass stops at the first encrypted entry (see stopAtEncryptedEntry), the archive is then read again with the password
+// of the provider. The entries passed to processEntry by the first pass are skipped by the second one.
+func (ap *archivePassword) extract(processEntry processEntryFunc,
+	extractPass func(password string, processEntry processEntryFunc, stopAtEncrypted bool) error) error {
+	processed := 0
+	for {
+		pending := ap.pending()
+		password := ""
+		if !pending {
+			var err error
+			if password, err = ap.get(); err != nil {
+				return err
+			}
+		}
+		skipped, passed := processed, 0
+		err := extractPass(password, func(header *ArchiveHeader) error {
+			if passed++; passed <= skipped {
+				return nil
+			}
+			return processEntry(header)
+		}, pending)
+		if !pending || !errors.Is(err, ErrEncrypted) {
+			return err
+		}
+		if _, err = ap.get(); err != nil {
+			return err
+		}
+		processed = passed
+	}
+}
+
+// stopAtEncryptedEntry returns ErrEncrypted instead of passing the encrypted entries to processEntry,
+// their content is tried before they are passed since 7z entries are only known to be encrypted once reading them fails
+func stopAtEncryptedEntry(processEntry processEntryFunc) processEntryFunc {
+	return func(header *ArchiveHeader) error {
+		if header.IsFolder || header.ArchiveReader == nil {
+			return processEntry(header)
+		}
+		first := make([]byte, 1)
+		n, err := header.ArchiveReader.Read(first)
+		if errors.Is(err, ErrEncrypted) {
+			return err
+		}
+		reader := header.ArchiveReader
+		if err != nil {
+			reader = errorReader{err: err}
+		}
+		header.ArchiveReader = io.MultiReader(bytes.NewReader(first[:n]), reader)
+		return processEntry(header)
+	}
+}
+
+// extractorPassword returns the password the mholt/archives extractor decrypts the entries with
+func extractorPassword(ex archives.Extractor) string {
+	switch extractor := ex.(type) {
+	case archives.SevenZip:
+		return extractor.Password
+	case archives.Rar:
+		return extractor.Password
+	}
+	return ""
+}
+
+// encryptionError converts the errors of the decompression libraries caused by the encryption to ErrEncrypted,
+// or to ErrWrongPassword when a password was given. Other errors are returned as is.
+func encryptionError(err error, password string) error {
+	var sevenZipErr *sevenzip.ReadError
+	switch {
+	case err == nil || errors.Is(err, ErrEncrypted) || errors.Is(err, ErrWrongPassword):
+		return err
+	case errors.Is(err, rardecode.ErrArchiveEncrypted), errors.Is(err, rardecode.ErrArchivedFileEncrypted):
+		return fmt.Errorf("%w: %w", ErrEncrypted, err)
+	case errors.Is(err, rardecode.ErrBadPassword):
+		return fmt.Errorf("%w: %w", ErrWrongPassword, err)
+	case errors.As(err, &sevenZipErr) && sevenZipErr.Encrypted:
+		// 7z decrypts with an empty password as well, so a missing password can only be told by the password itself
+		if password == "" {
+			return fmt.Errorf("%w: %w", ErrEncrypted, err)
+		}
+		return fmt.Errorf("%w: %w", ErrWrongPassword, err)
+	}
+	return err
+}
+
+// encryptionErrorReader converts the read errors with encryptionError, and marks the header as encrypted when they are
+type encryptionErrorReader struct {
+	reader   io.Reader
+	header   *ArchiveHeader
+	password string
+}
+
+func (eer *encryptionErrorReader) Read(p []byte) (int, error) {
+	n, err := eer.reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = encryptionError(err, eer.password)
+		if errors.Is(err, ErrEncrypted) || errors.Is(err, ErrWrongPassword) {
+			eer.header.Encrypted = true
+		}
+	}
+	return n, err
+}
+
+// errorReader is the content of the entries that can't be read, e.g. encrypted entries without a password
+type errorReader struct {
+	err error
+}
+
+func (er errorReader) Read([]byte) (int, error) {
+	return 0, er.err
+}
+
+func (er errorReader) Close() error {
+	return nil
+}
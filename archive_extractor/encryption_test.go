@@ -0,0 +1,303 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/nwaples/rardecode/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const encryptedZipPassword = "s3cret"
+
+var encryptedZipContent = map[string]string{
+	"secret.txt":   "secret content\n",
+	"repeated.txt": strings.Repeat("repeated line\n", 200),
+}
+
+// readEntries reads every entry, the read errors are collected by entry name
+func readEntries(t *testing.T, archiver ResultArchiver, source *Source) (map[string]*ArchiveHeader, map[string]string, map[string]error) {
+	headers := map[string]*ArchiveHeader{}
+	contents := map[string]string{}
+	readErrors := map[string]error{}
+	_, err := archiver.ExtractWithResult(context.Background(), source, func(header *ArchiveHeader) error {
+		headers[header.Name] = header
+		content, err := io.ReadAll(header.ArchiveReader)
+		if err != nil {
+			readErrors[header.Name] = err
+			return nil
+		}
+		contents[header.Name] = string(content)
+		return nil
+	})
+	require.NoError(t, err)
+	return headers, contents, readErrors
+}
+
+func fileSource(t *testing.T, path string) *Source {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	return NewReaderAtSource(filepath.Base(path), f, fi.Size())
+}
+
+func TestZipCryptoPassword(t *testing.T) {
+	za := ZipArchiver{Password: encryptedZipPassword}
+	headers, contents, readErrors := readEntries(t, za, fileSource(t, "./fixtures/testencrypted.zip"))
+	assert.Empty(t, readErrors)
+	assert.Equal(t, encryptedZipContent, contents)
+	for _, header := range headers {
+		assert.True(t, header.Encrypted)
+	}
+}
+
+func TestZipCryptoNoPassword(t *testing.T) {
+	headers, contents, readErrors := readEntries(t, ZipArchiver{}, fileSource(t, "./fixtures/testencrypted.zip"))
+	assert.Empty(t, contents)
+	require.Len(t, headers, 2)
+	for name, header := range headers {
+		assert.True(t, header.Encrypted)
+		assert.ErrorIs(t, readErrors[name], ErrEncrypted)
+	}
+}
+
+func TestZipCryptoWrongPassword(t *testing.T) {
+	za := ZipArchiver{Password: "wrong"}
+	_, contents, readErrors := readEntries(t, za, fileSource(t, "./fixtures/testencrypted.zip"))
+	assert.Empty(t, contents)
+	require.Len(t, readErrors, 2)
+	for _, err := range readErrors {
+		assert.ErrorIs(t, err, ErrWrongPassword)
+	}
+}
+
+func TestPasswordProvider(t *testing.T) {
+	var calls []string
+	provider := func(archiveName string) (string, error) {
+		calls = append(calls, archiveName)
+		return encryptedZipPassword, nil
+	}
+	za := ZipArchiver{PasswordProvider: provider}
+	_, contents, readErrors := readEntries(t, za, fileSource(t, "./fixtures/testencrypted.zip"))
+	assert.Empty(t, readErrors)
+	assert.Equal(t, encryptedZipContent, contents)
+	assert.Equal(t, []string{"testencrypted.zip"}, calls)
+
+	// archives without encrypted entries don't need a password
+	calls = nil
+	_, _, readErrors = readEntries(t, za, fileSource(t, "./fixtures/testwithcontent.zip"))
+	assert.Empty(t, readErrors)
+	assert.Empty(t, calls)
+
+	providerErr := errors.New("password store unavailable")
+	za = ZipArchiver{PasswordProvider: func(string) (string, error) { return "", providerErr }}
+	err := za.ExtractArchive("./fixtures/testencrypted.zip", processingFunc, params())
+	assert.ErrorContains(t, err, providerErr.Error())
+}
+
+func TestEncryptedZipOptions(t *testing.T) {
+	contents := map[string]string{}
+	err := Extract("./fixtures/testencrypted.zip", func(header *ArchiveHeader, params map[string]interface{}) error {
+		content, err := io.ReadAll(header.ArchiveReader)
+		contents[header.Name] = string(content)
+		return err
+	}, params(), WithPassword(encryptedZipPassword))
+	require.NoError(t, err)
+	assert.Equal(t, encryptedZipContent, contents)
+
+	afs, err := OpenFS("./fixtures/testencrypted.zip", WithPassword(encryptedZipPassword))
+	require.NoError(t, err)
+	defer afs.Close()
+	content, err := fs.ReadFile(afs, "secret.txt")
+	require.NoError(t, err)
+	assert.Equal(t, encryptedZipContent["secret.txt"], string(content))
+}
+
+// winZipAESZip returns a zip file holding content encrypted with WinZip AES-256 (AE-2)
+func winZipAESZip(t *testing.T, name string, content []byte, password string) []byte {
+	compressed := &bytes.Buffer{}
+	fw, err := flate.NewWriter(compressed, flate.BestCompression)
+	require.NoError(t, err)
+	_, err = fw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, fw.Close())
+
+	salt := make([]byte, 16)
+	_, err = rand.Read(salt)
+	require.NoError(t, err)
+	keys, err := pbkdf2.Key(sha1.New, password, salt, 1000, 66)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(keys[:32])
+	require.NoError(t, err)
+	encrypted := compressed.Bytes()
+	counter := make([]byte, aes.BlockSize)
+	keyStream := make([]byte, aes.BlockSize)
+	for i := 0; i < len(encrypted); i++ {
+		if i%aes.BlockSize == 0 {
+			binary.LittleEndian.PutUint64(counter, uint64(i/aes.BlockSize+1))
+			block.Encrypt(keyStream, counter)
+		}
+		encrypted[i] ^= keyStream[i%aes.BlockSize]
+	}
+	mac := hmac.New(sha1.New, keys[32:64])
+	mac.Write(encrypted)
+	raw := append(append(append(salt, keys[64:]...), encrypted...), mac.Sum(nil)[:10]...)
+
+	extra := []byte{0x01, 0x99, 7, 0, 2, 0, 'A', 'E', 3, byte(zip.Deflate), 0}
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{Name: name, Method: zipMethodAES, Flags: zipFlagEncrypted, Extra: extra,
+		CompressedSize64: uint64(len(raw)), UncompressedSize64: uint64(len(content))})
+	require.NoError(t, err)
+	_, err = w.Write(raw)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestWinZipAES(t *testing.T) {
+	content := []byte(strings.Repeat("aes encrypted content\n", 100))
+	data := winZipAESZip(t, "aes.txt", content, encryptedZipPassword)
+	source := func() *Source {
+		return NewReaderAtSource("aes.zip", bytes.NewReader(data), int64(len(data)))
+	}
+
+	headers, contents, readErrors := readEntries(t, ZipArchiver{Password: encryptedZipPassword}, source())
+	assert.Empty(t, readErrors)
+	assert.Equal(t, map[string]string{"aes.txt": string(content)}, contents)
+	assert.True(t, headers["aes.txt"].Encrypted)
+
+	_, _, readErrors = readEntries(t, ZipArchiver{}, source())
+	assert.ErrorIs(t, readErrors["aes.txt"], ErrEncrypted)
+
+	_, _, readErrors = readEntries(t, ZipArchiver{Password: "wrong"}, source())
+	assert.ErrorIs(t, readErrors["aes.txt"], ErrWrongPassword)
+
+	// the authentication code covers the encrypted content
+	tampered := bytes.Clone(data)
+	dataOffset := bytes.Index(tampered, []byte("aes.txt")) + len("aes.txt") + 11
+	tampered[dataOffset+20] ^= 0xff
+	_, _, readErrors = readEntries(t, ZipArchiver{Password: encryptedZipPassword},
+		NewReaderAtSource("aes.zip", bytes.NewReader(tampered), int64(len(tampered))))
+	assert.Error(t, readErrors["aes.txt"])
+}
+
+func TestEncryptionError(t *testing.T) {
+	sevenZipErr := &sevenzip.ReadError{Encrypted: true, Err: errors.New("lzma: corrupt data")}
+	assert.ErrorIs(t, encryptionError(sevenZipErr, ""), ErrEncrypted)
+	assert.ErrorIs(t, encryptionError(sevenZipErr, "password"), ErrWrongPassword)
+	assert.ErrorIs(t, encryptionError(sevenZipErr, "password"), sevenZipErr.Err)
+	plainErr := &sevenzip.ReadError{Err: errors.New("lzma: corrupt data")}
+	assert.Equal(t, plainErr, encryptionError(plainErr, ""))
+
+	assert.ErrorIs(t, encryptionError(rardecode.ErrArchiveEncrypted, ""), ErrEncrypted)
+	assert.ErrorIs(t, encryptionError(rardecode.ErrArchivedFileEncrypted, ""), ErrEncrypted)
+	assert.ErrorIs(t, encryptionError(rardecode.ErrBadPassword, "password"), ErrWrongPassword)
+	assert.Nil(t, encryptionError(nil, ""))
+}
+
+func TestSevenZipAndRarPassword(t *testing.T) {
+	// the password is not needed by archives which are not encrypted
+	var calls int
+	provider := func(string) (string, error) {
+		calls++
+		return "password", nil
+	}
+	headers := collect(t, SevenZipArchiver{PasswordProvider: provider}, "./fixtures/testwithcontent.7z").byPath()
+	assert.False(t, headers["compression.go"].Encrypted)
+	headers = collect(t, RarArchiver{Password: "password"}, "./fixtures/testwithcontent.rar").byPath()
+	assert.False(t, headers["compression.go"].Encrypted)
+	headers = collect(t, RarArchiver{PasswordProvider: provider}, "./fixtures/testwithcontent.rar").byPath()
+	assert.False(t, headers["compression.go"].Encrypted)
+	assert.Zero(t, calls)
+}
+
+func TestArchivePasswordExtract(t *testing.T) {
+	names := []string{"a.txt", "b.txt", "secret.txt", "c.txt"}
+	// the archive is encrypted from secret.txt on, its entries are read with the password
+	extractPass := func(password string, processEntry processEntryFunc, stopAtEncrypted bool) error {
+		if stopAtEncrypted {
+			processEntry = stopAtEncryptedEntry(processEntry)
+		}
+		for _, name := range names {
+			var content io.Reader = strings.NewReader(name)
+			if name == "secret.txt" && password != encryptedZipPassword {
+				content = errorReader{err: ErrEncrypted}
+			}
+			if err := processEntry(&ArchiveHeader{Name: name, ArchiveReader: content}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var calls int
+	var contents []string
+	password := newArchivePassword("test.7z", "", func(string) (string, error) {
+		calls++
+		return encryptedZipPassword, nil
+	})
+	err := password.extract(func(header *ArchiveHeader) error {
+		content, err := io.ReadAll(header.ArchiveReader)
+		contents = append(contents, string(content))
+		return err
+	}, extractPass)
+	require.NoError(t, err)
+	// the entries processed before the encrypted one are not processed again
+	assert.Equal(t, names, contents)
+	assert.Equal(t, 1, calls)
+
+	// without the password, the encrypted entry is processed and reading it fails
+	contents = nil
+	password = newArchivePassword("test.7z", "", func(string) (string, error) { return "", nil })
+	readErrors := map[string]error{}
+	err = password.extract(func(header *ArchiveHeader) error {
+		content, err := io.ReadAll(header.ArchiveReader)
+		contents = append(contents, string(content))
+		readErrors[header.Name] = err
+		return nil
+	}, extractPass)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b.txt", "", "c.txt"}, contents)
+	assert.ErrorIs(t, readErrors["secret.txt"], ErrEncrypted)
+
+	// the headers of the archive are encrypted, nothing is processed before the password is known
+	calls = 0
+	password = newArchivePassword("test.7z", "", func(string) (string, error) {
+		calls++
+		return encryptedZipPassword, nil
+	})
+	err = password.extract(func(*ArchiveHeader) error { return nil }, func(password string, processEntry processEntryFunc, stopAtEncrypted bool) error {
+		if password != encryptedZipPassword {
+			return ErrEncrypted
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	providerErr := errors.New("password store unavailable")
+	password = newArchivePassword("test.7z", "", func(string) (string, error) { return "", providerErr })
+	err = password.extract(func(*ArchiveHeader) error { return nil }, extractPass)
+	assert.ErrorIs(t, err, providerErr)
+}
@@ -0,0 +1,83 @@
+package archive_extractor
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// errStopIteration is returned to the archivers when the loop over Entries is exited early
+var errStopIteration = errors.New("entries iteration stopped")
+
+// entries adapts an archiver to the iterator API.
+// The ArchiveReader of a header can be read only until the loop moves to the next entry.
+// An extraction error is yielded once, with a nil header, as the last element of the iteration.
+func entries(ctx context.Context, path string, archiver Archiver) iter.Seq2[*ArchiveHeader, error] {
+	return iterate(func(processEntry processEntryFunc) error {
+		return archiver.ExtractArchiveContext(ctx, path, func(header *ArchiveHeader, params map[string]interface{}) error {
+			return processEntry(header)
+		}, map[string]interface{}{})
+	})
+}
+
+// iterate adapts an extraction, which passes every entry to processEntry, to the iterator API
+func iterate(extract func(processEntry processEntryFunc) error) iter.Seq2[*ArchiveHeader, error] {
+	return func(yield func(*ArchiveHeader, error) bool) {
+		err := extract(func(header *ArchiveHeader) error {
+			if !yield(header, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIteration) {
+			yield(nil, err)
+		}
+	}
+}
+
+// Entries iterates over the entries of the archive, breaking out of the loop stops the extraction.
+// The entries are iterated one at a time, Workers is ignored.
+func (za ZipArchiver) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	za.Workers = 0
+	return entries(ctx, path, za)
+}
+
+// Entries iterates over the entries of the archive, breaking out of the loop stops the extraction
+func (ta TarArchiver) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, ta)
+}
+
+// Entries iterates over the entries of the archive, breaking out of the loop stops the extraction
+func (da DebArchiver) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, da)
+}
+
+// Entries iterates over the entries of the archive, breaking out of the loop stops the extraction
+func (ra RpmArchiver) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, ra)
+}
+
+// Entries iterates over the entries of the archive, breaking out of the loop stops the extraction
+func (sa SevenZipArchiver) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, sa)
+}
+
+// Entries iterates over the entries of the archive, breaking out of the loop stops the extraction
+func (ra RarArchiver) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, ra)
+}
+
+// Entries iterates over the decompressed file, breaking out of the loop stops the extraction
+func (dc Decompressor) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, dc)
+}
+
+// Entries iterates over the decompressed metadata file, breaking out of the loop stops the extraction
+func (ga GzMetadataArchiver) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, ga)
+}
+
+// Entries iterates over the entries of the archive and of its nested archives, breaking out of the loop stops the extraction
+func (re RecursiveExtractor) Entries(ctx context.Context, path string) iter.Seq2[*ArchiveHeader, error] {
+	return entries(ctx, path, re)
+}
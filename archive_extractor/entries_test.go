@@ -0,0 +1,66 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"context"
+	"io"
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntries(t *testing.T) {
+	var testCases = []struct {
+		Name          string
+		Entries       iter.Seq2[*ArchiveHeader, error]
+		ExpectedCount int
+	}{
+		{"zip", ZipArchiver{}.Entries(context.Background(), "./fixtures/testwithmanyfiles.zip"), 100},
+		{"tar", TarArchiver{}.Entries(context.Background(), "./fixtures/testmanylarge.tar.gz"), 20},
+		{"deb", DebArchiver{}.Entries(context.Background(), "./fixtures/test.deb"), 3},
+		{"rpm", RpmArchiver{}.Entries(context.Background(), "./fixtures/test.rpm"), 15},
+		{"7z", SevenZipArchiver{}.Entries(context.Background(), "./fixtures/testwithmultipleentries.7z"), 2},
+		{"rar", RarArchiver{}.Entries(context.Background(), "./fixtures/testwithmanyfiles.rar"), 100},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			count := 0
+			for header, err := range tc.Entries {
+				require.NoError(t, err)
+				_, err = io.Copy(io.Discard, header.ArchiveReader)
+				require.NoError(t, err)
+				count++
+			}
+			assert.Equal(t, tc.ExpectedCount, count)
+		})
+	}
+}
+
+func TestEntriesBreak(t *testing.T) {
+	count := 0
+	for header, err := range (ZipArchiver{}).Entries(context.Background(), "./fixtures/testwithmanyfiles.zip") {
+		require.NoError(t, err)
+		require.NotNil(t, header)
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, count)
+}
+
+func TestEntriesLimitError(t *testing.T) {
+	var lastErr error
+	for header, err := range (TarArchiver{MaxNumberOfEntries: 12}).Entries(context.Background(), "./fixtures/testmanylarge.tar.gz") {
+		if err != nil {
+			assert.Nil(t, header)
+			lastErr = err
+			continue
+		}
+		_, _ = io.Copy(io.Discard, header.ArchiveReader)
+	}
+	assert.ErrorIs(t, lastErr, ErrTooManyEntries)
+}
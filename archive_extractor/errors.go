@@ -0,0 +1,72 @@
+package archive_extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+
+	"github.com/jfrog/go-rpm/v2"
+	"github.com/nwaples/rardecode/v2"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/jfrog/go-archive-extractor/compression"
+)
+
+// sevenZipKinds are the kinds of the errors of the 7z library, by their message. The library doesn't export these errors,
+// its only exported error is sevenzip.ReadError, which wraps the I/O errors classified on their own, so neither errors.Is
+// nor errors.As can match them. TestSevenZipKindsMessages fails if the library changes the messages.
+var sevenZipKinds = map[string]error{
+	"sevenzip: not a valid 7-zip file":                    archiver_errors.ErrNotThisFormat,
+	"sevenzip: checksum error":                            archiver_errors.ErrCorrupt,
+	"sevenzip: unsupported compression algorithm":         archiver_errors.ErrUnsupportedMethod,
+	"sevenzip: expected only one folder in header stream": archiver_errors.ErrCorrupt,
+}
+
+// classifyError adds its kind to an error of the decompression libraries (see archiver_errors.ErrCorrupt),
+// the other errors are returned as is
+func classifyError(err error) error {
+	if err == nil || archiver_errors.KindOf(err) != nil {
+		return err
+	}
+	switch err.(type) {
+	case *archiver_errors.MultiError, *archiver_errors.ArchiverExtractorError, *archiver_errors.OpenError:
+		// the typed errors keep their type, the errors they wrap are classified when they are created
+		return err
+	}
+	switch {
+	case errors.Is(err, rardecode.ErrNoSig), errors.Is(err, rpm.ErrNotRPMFile):
+		return archiver_errors.WithKind(archiver_errors.ErrNotThisFormat, err)
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, rardecode.ErrUnexpectedArcEnd),
+		errors.Is(err, rardecode.ErrDecoderOutOfData), errors.Is(err, rardecode.ErrShortFile), errors.Is(err, rardecode.ErrMultiVolume):
+		return archiver_errors.WithKind(archiver_errors.ErrTruncated, err)
+	case errors.Is(err, zip.ErrAlgorithm), errors.Is(err, rardecode.ErrUnknownDecoder), errors.Is(err, rardecode.ErrUnsupportedDecoder),
+		errors.Is(err, rardecode.ErrUnknownEncryptMethod), errors.Is(err, rardecode.ErrUnknownVersion):
+		return archiver_errors.WithKind(archiver_errors.ErrUnsupportedMethod, err)
+	case errors.Is(err, zip.ErrFormat), errors.Is(err, zip.ErrChecksum), errors.Is(err, gzip.ErrHeader), errors.Is(err, gzip.ErrChecksum), errors.Is(err, tar.ErrHeader),
+		errors.As(err, new(flate.CorruptInputError)), errors.As(err, new(bzip2.StructuralError)), compression.IsGetReaderError(err),
+		errors.Is(err, rardecode.ErrCorruptBlockHeader), errors.Is(err, rardecode.ErrCorruptFileHeader), errors.Is(err, rardecode.ErrBadHeaderCRC),
+		errors.Is(err, rardecode.ErrBadFileChecksum), errors.Is(err, rardecode.ErrCorruptDecodeHeader), errors.Is(err, rardecode.ErrHuffDecodeFailed),
+		errors.Is(err, rardecode.ErrInvalidLengthTable), errors.Is(err, rardecode.ErrCorruptPPM), errors.Is(err, rardecode.ErrInvalidFileBlock),
+		errors.Is(err, rardecode.ErrCorruptEncryptData), errors.Is(err, rpm.ErrNotHeader), errors.Is(err, rpm.ErrBadHeaderLength):
+		return archiver_errors.WithKind(archiver_errors.ErrCorrupt, err)
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if kind, ok := sevenZipKinds[e.Error()]; ok {
+			return archiver_errors.WithKind(kind, err)
+		}
+	}
+	return err
+}
+
+// classifyCallbackError wraps the errors of the processing function in a CallbackError,
+// unless they already have a kind, e.g. the errors reading the entry
+func classifyCallbackError(err error) error {
+	if err == nil || errors.Is(err, SkipEntry) || errors.Is(err, StopExtraction) || archiver_errors.KindOf(err) != nil {
+		return err
+	}
+	return &archiver_errors.CallbackError{Err: err}
+}
@@ -0,0 +1,138 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKinds(t *testing.T) {
+	text := writeTestFile(t, "file.txt", bytes.Repeat([]byte("not an archive\n"), 100))
+	content := bytes.Repeat([]byte("content"), 100)
+	tarContent := tarBytes(t, testEntry{name: "a.txt", content: content})
+	zipContent := zipBytes(t, testEntry{name: "a.txt", content: content})
+	// the stored entries are not compressed, so flipping a byte of their content fails the checksum
+	storedZip := &bytes.Buffer{}
+	zw := zip.NewWriter(storedZip)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "a.txt", Method: zip.Store})
+	require.NoError(t, err)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	corruptZip := bytes.Replace(storedZip.Bytes(), []byte("content"), []byte("CONTENT"), 1)
+
+	tests := []struct {
+		name     string
+		archiver Archiver
+		path     string
+		kind     error
+	}{
+		{"zip not a zip", ZipArchiver{}, text, archiver_errors.ErrNotThisFormat},
+		{"zoneinfo not a zip", ZipArchiver{}, "./fixtures/testzoneinfo.zi", archiver_errors.ErrNotThisFormat},
+		{"rar not a rar", RarArchiver{}, text, archiver_errors.ErrNotThisFormat},
+		{"7z not a 7z", SevenZipArchiver{}, "./fixtures/notRarFile.rar", archiver_errors.ErrNotThisFormat},
+		{"rpm not an rpm", RpmArchiver{}, text, archiver_errors.ErrNotThisFormat},
+		{"not compressed", Decompressor{}, text, archiver_errors.ErrNotThisFormat},
+		{"tar truncated", TarArchiver{}, writeTestFile(t, "truncated.tar", tarContent[:700]), archiver_errors.ErrTruncated},
+		{"gzip truncated", Decompressor{}, writeTestFile(t, "truncated.gz", gzipBytes(t, content)[:20]), archiver_errors.ErrTruncated},
+		{"zip corrupt", ZipArchiver{}, writeTestFile(t, "corrupt.zip", corruptZip), archiver_errors.ErrCorrupt},
+		{"zip too many entries", ZipArchiver{MaxNumberOfEntries: 1}, writeTestFile(t, "many.zip",
+			zipBytes(t, testEntry{name: "a.txt"}, testEntry{name: "b.txt"})), archiver_errors.ErrLimitExceeded},
+		{"zip compress ratio", ZipArchiver{MaxCompressRatio: 1}, writeTestFile(t, "ratio.zip", zipContent), archiver_errors.ErrLimitExceeded},
+		{"tar entry size", TarArchiver{EntryOptions: EntryOptions{MaxEntrySize: 10}}, writeTestFile(t, "large.tar", tarContent), archiver_errors.ErrLimitExceeded},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.archiver.ExtractArchive(test.path, processingReadingFunc, params())
+			require.Error(t, err)
+			assert.ErrorIs(t, err, test.kind)
+			assert.Equal(t, test.kind, archiver_errors.KindOf(err))
+		})
+	}
+}
+
+func TestErrorKindsKeepTypes(t *testing.T) {
+	err := ZipArchiver{}.ExtractArchive("./fixtures/testzoneinfo.zi", processingFunc, params())
+	assert.IsType(t, &ZoneInfoFileError{}, err)
+
+	err = RarArchiver{}.ExtractArchive("./fixtures/notRarFile.rar", processingFunc, params())
+	var openErr *archiver_errors.OpenError
+	assert.ErrorAs(t, err, &openErr)
+	assert.ErrorIs(t, err, archiver_errors.ErrNotThisFormat)
+
+	assert.ErrorIs(t, ErrWrongPassword, archiver_errors.ErrEncrypted)
+	assert.ErrorIs(t, ErrTooManyEntries, archiver_errors.ErrLimitExceeded)
+	assert.ErrorIs(t, ErrUnknownFormat, archiver_errors.ErrNotThisFormat)
+	assert.Equal(t, "too many entries in archive", ErrTooManyEntries.Error())
+}
+
+func TestErrorKindsUnsupportedMethod(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{Name: "data.bin", Method: 99, CompressedSize64: 4, UncompressedSize64: 4})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	err = ZipArchiver{}.ExtractArchive(writeTestFile(t, "method.zip", buf.Bytes()), processingFunc, params())
+	assert.ErrorIs(t, err, archiver_errors.ErrUnsupportedMethod)
+	// the entries that can't be opened are still reported together
+	var aee *archiver_errors.ArchiverExtractorError
+	assert.ErrorAs(t, err, &aee)
+}
+
+func TestCallbackError(t *testing.T) {
+	path := writeTestFile(t, "callback.zip", zipBytes(t, testEntry{name: "a.txt", content: []byte("a")}))
+	callbackErr := errors.New("callback failed")
+	for _, archiver := range []Archiver{ZipArchiver{}, RecursiveExtractor{MaxDepth: DefaultMaxDepth}} {
+		_, err := ExtractTyped(context.Background(), archiver, path, func(*ArchiveHeader, any) error {
+			return callbackErr
+		}, nil)
+		assert.ErrorIs(t, err, archiver_errors.ErrCallback)
+		assert.ErrorIs(t, err, callbackErr)
+		assert.Equal(t, callbackErr.Error(), err.Error())
+		var typedErr *archiver_errors.CallbackError
+		assert.ErrorAs(t, err, &typedErr)
+	}
+
+	// the errors reading the entries keep their kind when the processing function returns them
+	err := TarArchiver{EntryOptions: EntryOptions{MaxEntrySize: 10}}.ExtractArchive(writeTestFile(t, "large.tar",
+		tarBytes(t, testEntry{name: "a.txt", content: bytes.Repeat([]byte("a"), 100)})), processingReadingFunc, params())
+	assert.ErrorIs(t, err, archiver_errors.ErrLimitExceeded)
+	assert.NotErrorIs(t, err, archiver_errors.ErrCallback)
+}
+
+func TestSevenZipKindsMessages(t *testing.T) {
+	// the errors of the 7z library are matched by their message, which must still be the one it returns
+	data, err := os.ReadFile("./fixtures/test.7z")
+	require.NoError(t, err)
+	// the start header follows the signature header and its CRC
+	badChecksum := bytes.Clone(data)
+	badChecksum[20] ^= 0xff
+	tests := []struct {
+		name string
+		data []byte
+		kind error
+	}{
+		{"not a 7z", []byte(strings.Repeat("not a 7z", 10)), archiver_errors.ErrNotThisFormat},
+		{"bad checksum", badChecksum, archiver_errors.ErrCorrupt},
+	}
+	for _, test := range tests {
+		_, err := sevenzip.NewReader(bytes.NewReader(test.data), int64(len(test.data)))
+		require.Error(t, err, test.name)
+		assert.Equal(t, test.kind, sevenZipKinds[err.Error()], test.name)
+		assert.ErrorIs(t, classifyError(err), test.kind, test.name)
+	}
+}
@@ -0,0 +1,295 @@
+package archive_extractor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+)
+
+const DefaultUmask fs.FileMode = 022
+
+const maxSymlinksFollowed = 40
+
+// ErrUnsafePath is reported for the entries whose path, or whose symlink target, is outside of the destination folder
+var ErrUnsafePath = errors.New("path is outside of the destination folder")
+
+// ExtractToDir identifies the format of the archive stored in path (see Identify) and writes its entries under dest,
+// which is created if it doesn't exist. MaxCompressRatio and MaxNumberOfEntries are enforced like when extracting.
+// All the files are written through an os.Root, so neither the entries nor the symlinks found in dest can write outside of it.
+// Entries with an absolute path or with ".." elements are not written, and neither are the symlinks whose
+// target is outside of dest, their ErrUnsafePath errors are returned as a MultiError once the other entries are written.
+// The modes of the archive, or 0666 for files and 0777 for folders when the archive has none, are applied
+// without the Umask bits. Hardlinks are created when their target was written, special files (devices, fifos and sockets) are skipped.
+// The entries are written one at a time, Workers is ignored.
+func ExtractToDir(path, dest string, options ...Option) error {
+	return ExtractToDirContext(context.Background(), path, dest, options...)
+}
+
+func ExtractToDirContext(ctx context.Context, path, dest string, options ...Option) error {
+	options = append(slices.Clip(options), WithWorkers(0))
+	config := newArchiverConfig(options)
+	identification, err := Identify(path, options...)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dest, 0777&^config.Umask); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(dest)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	dw := &dirWriter{root: root, config: config}
+	err = identification.Archiver.ExtractArchiveContext(ctx, path, func(header *ArchiveHeader, params map[string]interface{}) error {
+		return dw.write(header)
+	}, map[string]interface{}{})
+	// the errors writing the entries are not errors of a processing function of the caller
+	if callbackErr, ok := err.(*archiver_errors.CallbackError); ok {
+		err = callbackErr.Err
+	}
+	if chmodErr := dw.chmodFolders(); err == nil {
+		err = chmodErr
+	}
+	if dw.multiErrors == nil {
+		return err
+	}
+	//multi error can be skipped or not skipped by caller, therefore we distinguish between err and multiErrors
+	if err == nil {
+		return dw.multiErrors
+	}
+	if multiErrors, ok := err.(*archiver_errors.MultiError); ok {
+		multiErrors.Errors = append(multiErrors.Errors, dw.multiErrors.Errors...)
+	}
+	return err
+}
+
+type dirWriter struct {
+	root        *os.Root
+	config      *ArchiverConfig
+	folders     []folderMode
+	multiErrors *archiver_errors.MultiError
+}
+
+// folderMode is applied once the extraction is done, so the entries of read only folders can be written
+type folderMode struct {
+	name string
+	perm fs.FileMode
+}
+
+func (dw *dirWriter) write(header *ArchiveHeader) error {
+	name, err := localPath(header.Name)
+	if err != nil {
+		dw.multiErrors = archiver_errors.Append(dw.multiErrors, archiver_errors.NewArchiverExtractorError(header.Name, err))
+		return nil
+	}
+	if name == "." || header.ViaSymlink != "" {
+		// the paths through the symlinks to folders are created by the symlinks themselves
+		return nil
+	}
+	entryType := header.Type
+	if entryType == EntryRegular {
+		// archivers outside of this package may only set the mode
+		entryType = entryTypeOf(header.Mode)
+	}
+	switch {
+	case header.IsFolder || entryType == EntryDir:
+		return dw.mkdir(name, header.Mode)
+	case entryType == EntrySymlink:
+		if !dw.config.Symlinks {
+			return nil
+		}
+		header.Type = EntrySymlink
+		return dw.symlink(name, header)
+	case entryType == EntryHardlink:
+		if len(header.Aliases) == 0 {
+			return dw.hardlink(header.Name, header.LinkTarget)
+		}
+		for _, alias := range header.Aliases {
+			if err = dw.hardlink(alias, header.LinkTarget); err != nil {
+				return err
+			}
+		}
+		return nil
+	case entryType != EntryRegular:
+		return nil
+	default:
+		return dw.writeFile(name, header)
+	}
+}
+
+// hardlink links the entry linkName to the entry target, the hardlinks whose target wasn't written are skipped
+func (dw *dirWriter) hardlink(linkName, target string) error {
+	name, err := localPath(linkName)
+	if err == nil {
+		target, err = localPath(target)
+	}
+	if err != nil {
+		dw.multiErrors = archiver_errors.Append(dw.multiErrors, archiver_errors.NewArchiverExtractorError(linkName, err))
+		return nil
+	}
+	if err = dw.mkdirParent(name); err != nil {
+		return err
+	}
+	if err = dw.root.Link(target, name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		dw.multiErrors = archiver_errors.Append(dw.multiErrors, archiver_errors.NewArchiverExtractorError(linkName, err))
+	}
+	return nil
+}
+
+// localPath converts the name of an entry to a path relative to the destination folder
+func localPath(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(slashed) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrUnsafePath
+	}
+	for _, elem := range strings.Split(slashed, "/") {
+		if elem == ".." {
+			return "", ErrUnsafePath
+		}
+	}
+	local := filepath.FromSlash(path.Clean(slashed))
+	if !filepath.IsLocal(local) && local != "." {
+		return "", ErrUnsafePath
+	}
+	return local, nil
+}
+
+func (dw *dirWriter) perm(mode fs.FileMode, defaultPerm fs.FileMode) fs.FileMode {
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = defaultPerm
+	}
+	return perm &^ dw.config.Umask
+}
+
+func (dw *dirWriter) mkdir(name string, mode fs.FileMode) error {
+	if err := dw.root.MkdirAll(name, dw.perm(0, 0777)); err != nil {
+		return err
+	}
+	dw.folders = append(dw.folders, folderMode{name: name, perm: dw.perm(mode, 0777)})
+	return nil
+}
+
+func (dw *dirWriter) mkdirParent(name string) error {
+	if parent := filepath.Dir(name); parent != "." {
+		return dw.root.MkdirAll(parent, dw.perm(0, 0777))
+	}
+	return nil
+}
+
+func (dw *dirWriter) writeFile(name string, header *ArchiveHeader) error {
+	if err := dw.mkdirParent(name); err != nil {
+		return err
+	}
+	// an existing symlink is replaced rather than followed
+	if info, err := dw.root.Lstat(name); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		if err = dw.root.Remove(name); err != nil {
+			return err
+		}
+	}
+	perm := dw.perm(header.Mode, 0666)
+	f, err := dw.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, header.ArchiveReader)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return dw.root.Chmod(name, perm)
+}
+
+func (dw *dirWriter) symlink(name string, header *ArchiveHeader) error {
+	if err := header.readLinkTarget(); err != nil {
+		return err
+	}
+	linkTarget := header.LinkTarget
+	if !dw.insideRoot(filepath.ToSlash(filepath.Dir(name)), linkTarget) {
+		dw.multiErrors = archiver_errors.Append(dw.multiErrors, archiver_errors.NewArchiverExtractorError(header.Name, ErrUnsafePath))
+		return nil
+	}
+	if err := dw.mkdirParent(name); err != nil {
+		return err
+	}
+	// existing paths are not replaced by symlinks, as it would change where the symlinks already checked point to
+	if err := dw.root.Symlink(linkTarget, name); err != nil {
+		dw.multiErrors = archiver_errors.Append(dw.multiErrors, archiver_errors.NewArchiverExtractorError(header.Name, err))
+	}
+	return nil
+}
+
+// insideRoot tells whether target, followed from the folder dir, stays under the root.
+// The symlinks met on the way are followed, and ".." is only allowed after elements that exist,
+// so the target can't escape through symlinks created later.
+func (dw *dirWriter) insideRoot(dir, target string) bool {
+	var current []string
+	if dir != "." {
+		current = strings.Split(dir, "/")
+	}
+	_, _, ok := dw.resolve(current, target, 0)
+	return ok
+}
+
+// resolve follows target from the folder current, it returns the resolved path and whether an element of it doesn't exist
+func (dw *dirWriter) resolve(current []string, target string, followed int) ([]string, bool, bool) {
+	slashed := strings.ReplaceAll(target, "\\", "/")
+	if followed > maxSymlinksFollowed || path.IsAbs(slashed) || filepath.IsAbs(target) || filepath.VolumeName(target) != "" {
+		return nil, false, false
+	}
+	missing := false
+	for _, elem := range strings.Split(slashed, "/") {
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if missing || len(current) == 0 {
+				return nil, false, false
+			}
+			current = current[:len(current)-1]
+			continue
+		}
+		current = append(current, elem)
+		if missing {
+			continue
+		}
+		name := filepath.FromSlash(path.Join(current...))
+		info, err := dw.root.Lstat(name)
+		if err != nil {
+			missing = true
+			continue
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			continue
+		}
+		linkTarget, err := dw.root.Readlink(name)
+		if err != nil {
+			return nil, false, false
+		}
+		var ok bool
+		if current, missing, ok = dw.resolve(current[:len(current)-1], linkTarget, followed+1); !ok {
+			return nil, false, false
+		}
+	}
+	return current, missing, true
+}
+
+func (dw *dirWriter) chmodFolders() error {
+	for i := len(dw.folders) - 1; i >= 0; i-- {
+		if err := dw.root.Chmod(dw.folders[i].name, dw.folders[i].perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
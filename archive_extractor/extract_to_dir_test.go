@@ -0,0 +1,163 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertUnsafePaths(t *testing.T, err error, names ...string) {
+	var multiErrors *archiver_errors.MultiError
+	require.True(t, errors.As(err, &multiErrors), "expected a MultiError, got %v", err)
+	require.Len(t, multiErrors.Errors, len(names))
+	for i, name := range names {
+		assert.ErrorIs(t, multiErrors.Errors[i], ErrUnsafePath)
+		assert.Contains(t, multiErrors.Errors[i].Error(), name)
+	}
+}
+
+func TestExtractToDir(t *testing.T) {
+	path := writeTestFile(t, "test.zip", zipBytes(t,
+		testEntry{name: "bin/", mode: fs.ModeDir | 0755},
+		testEntry{name: "bin/run.sh", content: []byte("#!/bin/sh"), mode: 0777},
+		fileEntry("docs/readme.txt", "readme")))
+	dest := filepath.Join(t.TempDir(), "dest")
+	require.NoError(t, ExtractToDir(path, dest))
+
+	content, err := os.ReadFile(filepath.Join(dest, "docs", "readme.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "readme", string(content))
+	info, err := os.Stat(filepath.Join(dest, "bin", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0755), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(dest, "bin"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestExtractToDirUmask(t *testing.T) {
+	path := writeTestFile(t, "test.zip", zipBytes(t, testEntry{name: "run.sh", content: []byte("#!/bin/sh"), mode: 0777}))
+	dest := t.TempDir()
+	require.NoError(t, ExtractToDir(path, dest, WithUmask(077)))
+	info, err := os.Stat(filepath.Join(dest, "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0700), info.Mode().Perm())
+}
+
+func TestExtractToDirStream(t *testing.T) {
+	dest := t.TempDir()
+	require.NoError(t, ExtractToDir("./fixtures/test.tar.gz", dest))
+	_, err := os.Stat(filepath.Join(dest, "logRotator-1.0", "log_rotator.go"))
+	assert.NoError(t, err)
+}
+
+func TestExtractToDirTraversal(t *testing.T) {
+	path := writeTestFile(t, "test.zip", zipBytes(t,
+		fileEntry("../evil.txt", "evil"),
+		fileEntry("/abs.txt", "evil"),
+		fileEntry("a/../../evil2.txt", "evil"),
+		fileEntry("good.txt", "good")))
+	parent := t.TempDir()
+	dest := filepath.Join(parent, "dest")
+	err := ExtractToDir(path, dest)
+	assertUnsafePaths(t, err, "../evil.txt", "/abs.txt", "a/../../evil2.txt")
+
+	_, err = os.Stat(filepath.Join(parent, "evil.txt"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dest, "good.txt"))
+	assert.NoError(t, err)
+}
+
+func TestExtractToDirSymlinks(t *testing.T) {
+	path := writeTestFile(t, "test.zip", zipBytes(t,
+		fileEntry("lib/libfoo.so.1", "lib"),
+		symlinkEntry("lib/libfoo.so", "libfoo.so.1"),
+		symlinkEntry("escape", "../../etc/passwd"),
+		symlinkEntry("absolute", "/etc/passwd")))
+
+	dest := t.TempDir()
+	require.NoError(t, ExtractToDir(path, dest))
+	_, err := os.Lstat(filepath.Join(dest, "lib", "libfoo.so"))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "symlinks are skipped by default")
+
+	dest = t.TempDir()
+	err = ExtractToDir(path, dest, WithSymlinks(true))
+	assertUnsafePaths(t, err, "escape", "absolute")
+	target, err := os.Readlink(filepath.Join(dest, "lib", "libfoo.so"))
+	require.NoError(t, err)
+	assert.Equal(t, "libfoo.so.1", target)
+	_, err = os.Lstat(filepath.Join(dest, "escape"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestExtractToDirSymlinkThroughSymlink(t *testing.T) {
+	path := writeTestFile(t, "test.zip", zipBytes(t,
+		symlinkEntry("x", "."),
+		symlinkEntry("sub/y", "../x/.."),
+		// z is checked before w exists, and w could then point anywhere inside the root
+		symlinkEntry("z", "w/.."),
+		symlinkEntry("w", ".")))
+	dest := t.TempDir()
+	err := ExtractToDir(path, dest, WithSymlinks(true))
+	assertUnsafePaths(t, err, "sub/y", "z")
+}
+
+func TestExtractToDirDirectorySymlink(t *testing.T) {
+	path := writeTestFile(t, "symlinks.tar", tarBytes(t, fileEntry("usr/lib/libfoo.so.1", "foo"), symlinkEntry("lib", "usr/lib")))
+
+	dest := t.TempDir()
+	require.NoError(t, ExtractToDir(path, dest))
+	_, err := os.Lstat(filepath.Join(dest, "lib"))
+	assert.ErrorIs(t, err, fs.ErrNotExist, "the files under a symlink to a folder are not copied")
+
+	dest = t.TempDir()
+	require.NoError(t, ExtractToDir(path, dest, WithSymlinks(true)))
+	target, err := os.Readlink(filepath.Join(dest, "lib"))
+	require.NoError(t, err)
+	assert.Equal(t, "usr/lib", target)
+	content, err := os.ReadFile(filepath.Join(dest, "lib", "libfoo.so.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(content))
+}
+
+func TestExtractToDirExistingSymlink(t *testing.T) {
+	outside := t.TempDir()
+	dest := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(dest, "out")))
+	path := writeTestFile(t, "test.zip", zipBytes(t, fileEntry("out/file.txt", "evil")))
+	assert.Error(t, ExtractToDir(path, dest))
+	_, err := os.Stat(filepath.Join(outside, "file.txt"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestExtractToDirLimits(t *testing.T) {
+	err := ExtractToDir("./fixtures/testwithsinglelargefile.zip", t.TempDir(), WithMaxCompressRatio(1))
+	assert.True(t, IsErrCompressLimitReached(err))
+	err = ExtractToDir("./fixtures/testwithmanyfiles.zip", t.TempDir(), WithMaxNumberOfEntries(10))
+	assert.ErrorIs(t, err, ErrTooManyEntries)
+}
+
+func TestExtractToDirHardlinks(t *testing.T) {
+	path := tarWithLinks(t)
+	for _, hardlinkAliases := range []bool{false, true} {
+		dest := t.TempDir()
+		require.NoError(t, ExtractToDir(path, dest, WithHardlinkAliases(hardlinkAliases)))
+		target, err := os.Stat(filepath.Join(dest, "lib", "libfoo.so.1"))
+		require.NoError(t, err)
+		for _, name := range []string{"lib/libfoo-copy.so", "bin/foo"} {
+			link, err := os.Stat(filepath.Join(dest, name))
+			require.NoError(t, err)
+			assert.True(t, os.SameFile(target, link), name)
+		}
+		_, err = os.Lstat(filepath.Join(dest, "bin", "missing"))
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	}
+}
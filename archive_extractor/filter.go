@@ -0,0 +1,78 @@
+package archive_extractor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// EntryFilter selects the entries passed to the processing function, the zero value selects them all.
+// The regular files of zip, 7z and rar archives are matched before being opened, so the excluded ones are not decompressed,
+// and the excluded regular files of tar archives are neither read nor spooled.
+type EntryFilter struct {
+	// Include and Exclude are doublestar patterns (e.g. "**/*.jar"). A pattern without a slash is matched against
+	// the base name of the entries, like in .gitignore files, the other patterns against their whole path.
+	// An entry is selected when it matches one of the Include patterns, if any, and none of the Exclude patterns.
+	// The patterns are matched against the name the entry is reported with, normalised as set by EntryOptions.Names.
+	Include []string
+	Exclude []string
+	// Predicate is called with the header of the entries matching the patterns, the entry is selected when it returns true.
+	// It is called before the entry is opened, ArchiveReader must not be read.
+	Predicate func(*ArchiveHeader) bool
+}
+
+func (f EntryFilter) validate() error {
+	for _, pattern := range append(f.Include[:len(f.Include):len(f.Include)], f.Exclude...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid entry filter pattern: %q", pattern)
+		}
+	}
+	return nil
+}
+
+// match tells whether the filter selects the entry under the name it is reported with, given the names normalisation.
+// The entries matched before being opened don't have their normalised name yet.
+func (f EntryFilter) match(header *ArchiveHeader, names NameNormalization) bool {
+	if names != NamesDefault {
+		normalized := *header
+		normalized.Name = names.normalize(header.RawName)
+		normalized.Path = normalized.Name
+		header = &normalized
+	}
+	name := cleanArchivePath(header.Name)
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+	if matchAny(f.Exclude, name) {
+		return false
+	}
+	return f.Predicate == nil || f.Predicate(header)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if doublestar.MatchUnvalidated(pattern, path.Base(name)) {
+				return true
+			}
+		} else if doublestar.MatchUnvalidated(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply skips the entries the filter doesn't select, unless they were selected before being opened
+func (f EntryFilter) apply(names NameNormalization, processEntry processEntryFunc) processEntryFunc {
+	if len(f.Include) == 0 && len(f.Exclude) == 0 && f.Predicate == nil {
+		return processEntry
+	}
+	return func(header *ArchiveHeader) error {
+		if !header.selected && !f.match(header, names) {
+			return nil
+		}
+		return processEntry(header)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryFilterMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  EntryFilter
+		path    string
+		matches bool
+	}{
+		{"no filter", EntryFilter{}, "a/b.txt", true},
+		{"base name", EntryFilter{Include: []string{"*.jar"}}, "lib/a.jar", true},
+		{"base name mismatch", EntryFilter{Include: []string{"*.jar"}}, "lib/a.war", false},
+		{"exact base name", EntryFilter{Include: []string{"package.json"}}, "node_modules/x/package.json", true},
+		{"whole path", EntryFilter{Include: []string{"lib/*.jar"}}, "lib/a.jar", true},
+		{"whole path mismatch", EntryFilter{Include: []string{"lib/*.jar"}}, "app/lib/a.jar", false},
+		{"double star", EntryFilter{Include: []string{"**/META-INF/*.MF"}}, "x/y/META-INF/MANIFEST.MF", true},
+		{"cleaned path", EntryFilter{Include: []string{"lib/*.jar"}}, "./lib/a.jar", true},
+		{"excluded", EntryFilter{Include: []string{"*.jar"}, Exclude: []string{"test/**"}}, "test/a.jar", false},
+		{"predicate", EntryFilter{Predicate: func(header *ArchiveHeader) bool { return header.Size < 10 }}, "a.txt", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.matches, test.filter.match(&ArchiveHeader{Name: test.path, Size: 5}, NamesDefault))
+		})
+	}
+}
+
+func TestEntryFilterInvalidPattern(t *testing.T) {
+	za := ZipArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Exclude: []string{"[a-"}}}}
+	err := za.ExtractArchive("./fixtures/testwithcontent.zip", processingFunc, params())
+	assert.ErrorContains(t, err, "invalid entry filter pattern")
+}
+
+func TestZipArchiverFilterSkipsOpening(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("lib/a.jar")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("jar"))
+	require.NoError(t, err)
+	// opening an entry compressed with an unknown method fails
+	w, err = zw.CreateRaw(&zip.FileHeader{Name: "data.bin", Method: 99, CompressedSize64: 4, UncompressedSize64: 4})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	path := writeTestFile(t, "filter.zip", buf.Bytes())
+
+	err = ZipArchiver{}.ExtractArchive(path, processingFunc, params())
+	assert.ErrorContains(t, err, "unsupported compression algorithm")
+
+	result, err := ExtractTyped(context.Background(), ZipArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Include: []string{"*.jar"}}}}, path,
+		func(header *ArchiveHeader, names *[]string) error {
+			*names = append(*names, header.Name)
+			return nil
+		}, &[]string{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.EntriesCount)
+}
+
+func TestZipArchiverFilterNormalizedNames(t *testing.T) {
+	path := writeTestFile(t, "backslashes.zip", zipBytes(t, fileEntry(`lib\a.jar`, "a"), fileEntry(`test\b.jar`, "b"), fileEntry(`lib\c.txt`, "c")))
+	// the patterns are matched against the names the entries are reported with, before and after being opened
+	included := collect(t, ZipArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Include: []string{"lib/*.jar"}}, Names: NamesRelative}}, path)
+	assert.Equal(t, []string{"lib/a.jar"}, included.names())
+	excluded := collect(t, ZipArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Include: []string{"*.jar"}, Exclude: []string{"test/**"}}, Names: NamesClean}}, path)
+	assert.Equal(t, []string{"lib/a.jar"}, excluded.names())
+	// without normalisation the backslashes are part of the base name
+	raw := collect(t, ZipArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Include: []string{"lib/*.jar"}}}}, path)
+	assert.Empty(t, raw.names())
+}
+
+func TestTarArchiverFilterLinks(t *testing.T) {
+	path := writeTestFile(t, "symlinks.tar", tarBytes(t, fileEntry("usr/lib/libbar.so.1", "bar"),
+		fileEntry("usr/lib/libfoo.so.1", "foo"),
+		symlinkEntry("lib", "usr/lib"),
+		symlinkEntry("usr/lib/libfoo.so", "libfoo.so.1")))
+	// the target of the selected link is read again
+	assert.Equal(t, map[string]string{
+		"usr/lib/libfoo.so": "foo",
+		"lib/libfoo.so":     "foo",
+	}, collect(t, TarArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Include: []string{"*.so"}}}}, path).contents())
+	assert.Equal(t, map[string]string{
+		"usr/lib/libfoo.so.1": "foo",
+		"usr/lib/libbar.so.1": "bar",
+		"usr/lib/libfoo.so":   "foo",
+	}, collect(t, TarArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Exclude: []string{"lib/**"}}}}, path).contents())
+}
+
+func TestSevenZipArchiverFilter(t *testing.T) {
+	var predicateHeaders []string
+	filter := EntryFilter{
+		Include: []string{"libfoo.so.1", "dangling"},
+		Predicate: func(header *ArchiveHeader) bool {
+			predicateHeaders = append(predicateHeaders, header.Name)
+			return header.Name != "lib/libfoo.so.1"
+		},
+	}
+	assert.Equal(t, map[string]string{
+		"usr/lib/libfoo.so.1": "foo",
+		"dangling":            "missing",
+	}, collect(t, SevenZipArchiver{EntryOptions: EntryOptions{Filter: filter}}, "./fixtures/testsymlinks.7z").contents())
+	// the predicate is called once per entry matching the patterns
+	assert.ElementsMatch(t, []string{"usr/lib/libfoo.so.1", "lib/libfoo.so.1", "dangling"}, predicateHeaders)
+}
@@ -0,0 +1,161 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testEntry is an entry of the archives built by the tests, a regular file unless mode is a folder or a symlink.
+// link is the target of a symlink, or of a hardlink in tar archives when mode is not a symlink.
+type testEntry struct {
+	name    string
+	content []byte
+	mode    fs.FileMode
+	link    string
+}
+
+func fileEntry(name, content string) testEntry {
+	return testEntry{name: name, content: []byte(content)}
+}
+
+func symlinkEntry(name, target string) testEntry {
+	return testEntry{name: name, mode: fs.ModeSymlink | 0777, link: target}
+}
+
+func zipBytes(t *testing.T, entries ...testEntry) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.mode != 0 {
+			header.SetMode(entry.mode)
+		}
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		content := entry.content
+		if entry.mode&fs.ModeSymlink != 0 {
+			content = []byte(entry.link)
+		}
+		_, err = w.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func tarBytes(t *testing.T, entries ...testEntry) []byte {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: tar.TypeReg, Mode: int64(entry.mode.Perm()), Size: int64(len(entry.content))}
+		if header.Mode == 0 {
+			header.Mode = 0644
+		}
+		switch {
+		case entry.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+		case entry.mode&fs.ModeSymlink != 0:
+			header.Typeflag, header.Linkname = tar.TypeSymlink, entry.link
+		case entry.link != "":
+			header.Typeflag, header.Linkname = tar.TypeLink, entry.link
+		}
+		if header.Typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write(entry.content[:header.Size])
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	_, err := gw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	return path
+}
+
+func tarGzFile(t *testing.T, name string, entries ...testEntry) string {
+	return writeTestFile(t, name, gzipBytes(t, tarBytes(t, entries...)))
+}
+
+// extractedEntries are the entries of an archive in the order they were reported, with their content read
+type extractedEntries []extractedEntry
+
+type extractedEntry struct {
+	header  *ArchiveHeader
+	content []byte
+}
+
+// collect extracts the archive stored in path, the ArchiveReader of the headers reads their content again
+func collect(t *testing.T, archiver Archiver, path string) extractedEntries {
+	extracted, err := collectWithError(t, archiver, path)
+	require.NoError(t, err)
+	return extracted
+}
+
+// collectWithError is collect for the extractions which fail, the entries reported before the error are returned with it
+func collectWithError(t *testing.T, archiver Archiver, path string) (extractedEntries, error) {
+	var extracted extractedEntries
+	for header, err := range entries(context.Background(), path, archiver) {
+		if err != nil {
+			return extracted, err
+		}
+		content, err := io.ReadAll(header.ArchiveReader)
+		require.NoError(t, err)
+		require.Equal(t, header.IsFolder, header.Type == EntryDir, header.Name)
+		header.ArchiveReader = bytes.NewReader(content)
+		extracted = append(extracted, extractedEntry{header: header, content: content})
+	}
+	return extracted, nil
+}
+
+// byPath returns the headers by their Path, which is their Name unless they are nested in another archive
+func (ee extractedEntries) byPath() map[string]*ArchiveHeader {
+	headers := map[string]*ArchiveHeader{}
+	for _, entry := range ee {
+		headers[entry.header.Path] = entry.header
+	}
+	return headers
+}
+
+func (ee extractedEntries) names() []string {
+	var names []string
+	for _, entry := range ee {
+		names = append(names, entry.header.Name)
+	}
+	return names
+}
+
+// contents returns the content of the entries which are not folders by their Path
+func (ee extractedEntries) contents() map[string]string {
+	contents := map[string]string{}
+	for _, entry := range ee {
+		if !entry.header.IsFolder {
+			contents[entry.header.Path] = string(entry.content)
+		}
+	}
+	return contents
+}
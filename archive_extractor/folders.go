@@ -0,0 +1,85 @@
+package archive_extractor
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/jfrog/go-archive-extractor/utils"
+)
+
+// FolderReporting controls whether the folders of an archive are passed to processingFunc.
+// Folders are reported with IsFolder set, the type EntryDir and a name ending with a slash.
+// Placeholder entries (named "-") are never reported.
+type FolderReporting int
+
+const (
+	// FoldersDefault keeps the behaviour every archiver always had: ZipArchiver reports the folders
+	// stored in the archive, DebArchiver too when SkipFoldersCheck is set, the other archivers skip them
+	FoldersDefault FolderReporting = iota
+	// FoldersSkip skips all the folders
+	FoldersSkip
+	// FoldersExplicit reports the folders stored in the archive
+	FoldersExplicit
+	// FoldersAll also reports the implied folders, the folders containing entries but not stored in the archive.
+	// Every folder is reported once, before the first entry it contains.
+	FoldersAll
+)
+
+// or returns the reporting of the archiver, defaultReporting being the one it always had
+func (fr FolderReporting) or(defaultReporting FolderReporting) FolderReporting {
+	if fr == FoldersDefault {
+		return defaultReporting
+	}
+	return fr
+}
+
+// apply reports the folders to processEntry according to fr, and skips the placeholder entries
+func (fr FolderReporting) apply(processEntry processEntryFunc) processEntryFunc {
+	var mutex sync.Mutex
+	reported := map[string]bool{}
+	return func(header *ArchiveHeader) error {
+		name := cleanArchivePath(header.Name)
+		if fr == FoldersAll {
+			// the lock is held while the implied folders are processed, so that with concurrent workers (see ZipArchiver)
+			// they are still reported before the entries they contain
+			mutex.Lock()
+			err := reportImpliedFolders(path.Dir(name), reported, processEntry)
+			duplicate := header.IsFolder && reported[name]
+			reported[name] = true
+			mutex.Unlock()
+			if err != nil || duplicate {
+				return err
+			}
+		}
+		if utils.PlaceHolderFolder(name) {
+			return nil
+		}
+		if header.IsFolder {
+			// the root of the archive (e.g. "./" in tar archives) is not a folder of the archive
+			if fr == FoldersSkip || name == "." {
+				return nil
+			}
+			if !strings.HasSuffix(header.Name, utils.FolderSuffix) {
+				header.Name += utils.FolderSuffix
+				header.Path += utils.FolderSuffix
+			}
+		}
+		return processEntry(header)
+	}
+}
+
+// reportImpliedFolders reports folder and the folders containing it which aren't reported yet, the outermost first
+func reportImpliedFolders(folder string, reported map[string]bool, processEntry processEntryFunc) error {
+	if folder == "." || folder == "/" || reported[folder] {
+		return nil
+	}
+	if err := reportImpliedFolders(path.Dir(folder), reported, processEntry); err != nil {
+		return err
+	}
+	reported[folder] = true
+	header := NewArchiveHeader(strings.NewReader(""), folder+utils.FolderSuffix, 0, 0)
+	header.setMode(fs.ModeDir)
+	return processEntry(header)
+}
@@ -0,0 +1,54 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// folderEntries are stored in the test archives, opt/ and usr/lib/ are implied
+var folderEntries = []testEntry{
+	{name: "usr/", mode: fs.ModeDir | 0755},
+	fileEntry("usr/lib/libfoo.so", "foo"),
+	fileEntry("usr/-", ""),
+	fileEntry("opt/app/bin/run", "run"),
+}
+
+func TestFolderReporting(t *testing.T) {
+	tarPath := writeTestFile(t, "folders.tar", tarBytes(t, folderEntries...))
+	zipPath := writeTestFile(t, "folders.zip", zipBytes(t, folderEntries...))
+	files := []string{"usr/lib/libfoo.so", "opt/app/bin/run"}
+	explicit := []string{"usr/", "usr/lib/libfoo.so", "opt/app/bin/run"}
+	all := []string{"usr/", "usr/lib/", "usr/lib/libfoo.so", "opt/", "opt/app/", "opt/app/bin/", "opt/app/bin/run"}
+	tests := []struct {
+		folders  FolderReporting
+		tarNames []string
+		zipNames []string
+	}{
+		{FoldersDefault, files, explicit},
+		{FoldersSkip, files, files},
+		{FoldersExplicit, explicit, explicit},
+		{FoldersAll, all, all},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.tarNames, collect(t, TarArchiver{EntryOptions: EntryOptions{Folders: test.folders}}, tarPath).names(), "tar %d", test.folders)
+		assert.Equal(t, test.zipNames, collect(t, ZipArchiver{EntryOptions: EntryOptions{Folders: test.folders}}, zipPath).names(), "zip %d", test.folders)
+	}
+}
+
+func TestFolderReportingSevenZip(t *testing.T) {
+	names := collect(t, SevenZipArchiver{EntryOptions: EntryOptions{Folders: FoldersExplicit}}, "./fixtures/testsymlinks.7z").names()
+	assert.Contains(t, names, "usr/")
+	assert.Contains(t, names, "usr/lib/")
+	assert.NotContains(t, collect(t, SevenZipArchiver{}, "./fixtures/testsymlinks.7z").names(), "usr/")
+}
+
+func TestFolderHeader(t *testing.T) {
+	headers := collect(t, TarArchiver{EntryOptions: EntryOptions{Folders: FoldersAll}}, writeTestFile(t, "folders.tar", tarBytes(t, folderEntries...))).byPath()
+	assert.Equal(t, fs.ModeDir|0755, headers["usr/"].Mode)
+	assert.True(t, headers["usr/lib/"].IsFolder)
+	assert.Equal(t, EntryDir, headers["usr/lib/"].Type)
+}
@@ -1,6 +1,7 @@
 package archive_extractor
 
 import (
+	"context"
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/jfrog/go-archive-extractor/compression"
 	"time"
@@ -8,21 +9,50 @@ import (
 
 type GzMetadataArchiver struct {
 	MaxCompressRatio int64
+	EntryOptions
 }
 
 func (ga GzMetadataArchiver) ExtractArchive(path string,
 	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	return ga.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (ga GzMetadataArchiver) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ga.ExtractSource(ctx, source, processingFunc, params)
+}
+
+func (ga GzMetadataArchiver) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := ga.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+func (ga GzMetadataArchiver) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, ga.EntryOptions, FoldersSkip)
+	if err != nil {
+		return result, err
+	}
+	return result, result.finish(ga.extractEntries(ctx, source, processEntry))
+}
+
+func (ga GzMetadataArchiver) extractEntries(ctx context.Context, source *Source, processEntry processEntryFunc) error {
 
-	maxBytesLimit, err := maxBytesLimit(path, ga.MaxCompressRatio)
+	maxBytesLimit, err := source.maxBytesLimit(ga.MaxCompressRatio)
 	if err != nil {
 		return err
 	}
 	provider := LimitAggregatingReadCloserProvider{
 		Limit: maxBytesLimit,
 	}
-	cReader, _, err := compression.NewReader(path)
+	cReader, _, err := compression.NewReaderFrom(source.stream(), source.Name, compression.WithContext(ctx))
 	if compression.IsGetReaderError(err) {
-		return archiver_errors.New(err)
+		return archiver_errors.New(classifyError(err))
 	}
 	if err != nil {
 		return err
@@ -30,7 +60,7 @@ func (ga GzMetadataArchiver) ExtractArchive(path string,
 	countingReadCloser := provider.CreateLimitAggregatingReadCloser(cReader)
 	defer countingReadCloser.Close()
 	archiveHeader := NewArchiveHeader(countingReadCloser, "metadata", time.Now().Unix(), 0)
-	err = processingFunc(archiveHeader, params)
+	err = processEntry(archiveHeader)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,185 @@
+package archive_extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/mholt/archives"
+	"github.com/nwaples/rardecode/v2"
+)
+
+// maxLinkTargetSize bounds the content read from the symlink entries whose content is their target
+const maxLinkTargetSize = 4096
+
+// infoZipUnixExtraID is the zip extra field holding the uid and the gid of the entry
+const infoZipUnixExtraID = 0x7875
+
+// setMode sets the mode of the entry and the type matching it. Entries whose name ends with a slash are folders
+// whatever their mode.
+func (ah *ArchiveHeader) setMode(mode fs.FileMode) {
+	ah.Mode = mode
+	ah.Type = entryTypeOf(mode)
+	if ah.IsFolder && ah.Type == EntryRegular {
+		ah.Type = EntryDir
+	}
+	ah.IsFolder = ah.Type == EntryDir
+}
+
+func entryTypeOf(mode fs.FileMode) EntryType {
+	switch {
+	case mode.IsDir():
+		return EntryDir
+	case mode&fs.ModeSymlink != 0:
+		return EntrySymlink
+	case mode&fs.ModeNamedPipe != 0:
+		return EntryFifo
+	case mode&fs.ModeSocket != 0:
+		return EntrySocket
+	case mode&fs.ModeCharDevice != 0:
+		return EntryCharDevice
+	case mode&fs.ModeDevice != 0:
+		return EntryBlockDevice
+	}
+	return EntryRegular
+}
+
+// modeType returns the type bits of the fs.FileMode of the entry type, 0 for the regular files and the hardlinks
+func (et EntryType) modeType() fs.FileMode {
+	switch et {
+	case EntryDir:
+		return fs.ModeDir
+	case EntrySymlink:
+		return fs.ModeSymlink
+	case EntryFifo:
+		return fs.ModeNamedPipe
+	case EntrySocket:
+		return fs.ModeSocket
+	case EntryCharDevice:
+		return fs.ModeDevice | fs.ModeCharDevice
+	case EntryBlockDevice:
+		return fs.ModeDevice
+	}
+	return 0
+}
+
+// unixModeToFileMode converts the st_mode bits stored by Unix formats (e.g. ar) to a fs.FileMode
+func unixModeToFileMode(mode int64) fs.FileMode {
+	fileMode := fs.FileMode(mode).Perm()
+	if mode&04000 != 0 {
+		fileMode |= fs.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		fileMode |= fs.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		fileMode |= fs.ModeSticky
+	}
+	switch mode & 0170000 {
+	case 0040000:
+		fileMode |= fs.ModeDir
+	case 0120000:
+		fileMode |= fs.ModeSymlink
+	case 0010000:
+		fileMode |= fs.ModeNamedPipe
+	case 0140000:
+		fileMode |= fs.ModeSocket
+	case 0020000:
+		fileMode |= fs.ModeDevice | fs.ModeCharDevice
+	case 0060000:
+		fileMode |= fs.ModeDevice
+	}
+	return fileMode
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// setFileInfo fills the header from an entry extracted with mholt/archives
+func (ah *ArchiveHeader) setFileInfo(fileInfo archives.FileInfo) {
+	ah.setMode(fileInfo.Mode())
+	ah.LinkTarget = fileInfo.LinkTarget
+	ah.ModTimeNs = unixNano(fileInfo.ModTime())
+	switch header := fileInfo.Header.(type) {
+	case *tar.Header:
+		ah.Uid = header.Uid
+		ah.Gid = header.Gid
+		ah.Uname = header.Uname
+		ah.Gname = header.Gname
+		ah.AccessTimeNs = unixNano(header.AccessTime)
+		ah.ChangeTimeNs = unixNano(header.ChangeTime)
+		if header.Typeflag == tar.TypeLink {
+			ah.Type = EntryHardlink
+		}
+	case sevenzip.FileHeader:
+		ah.AccessTimeNs = unixNano(header.Accessed)
+	case *rardecode.FileHeader:
+		ah.AccessTimeNs = unixNano(header.AccessTime)
+		ah.CompressedSize = header.PackedSize
+		ah.Encrypted = header.Encrypted
+	}
+}
+
+// readLinkTarget sets LinkTarget of the symlinks stored with their target as content (e.g. in zip and 7z),
+// the content can still be read from ArchiveReader afterwards
+func (ah *ArchiveHeader) readLinkTarget() error {
+	if ah.Type != EntrySymlink || ah.LinkTarget != "" {
+		return nil
+	}
+	target, err := io.ReadAll(io.LimitReader(ah.ArchiveReader, maxLinkTargetSize))
+	if err != nil {
+		return err
+	}
+	ah.LinkTarget = string(target)
+	ah.ArchiveReader = bytes.NewReader(target)
+	return nil
+}
+
+// zipOwner returns the uid and the gid stored in the Info-ZIP Unix extra field, or zeros if there is none
+func zipOwner(extra []byte) (int, int) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if id == infoZipUnixExtraID {
+			return parseInfoZipUnixExtra(extra[4 : 4+size])
+		}
+		extra = extra[4+size:]
+	}
+	return 0, 0
+}
+
+// parseInfoZipUnixExtra parses the version, followed by the size and the little endian value of the uid and of the gid
+func parseInfoZipUnixExtra(data []byte) (int, int) {
+	if len(data) < 1 || data[0] != 1 {
+		return 0, 0
+	}
+	data = data[1:]
+	ids := make([]int, 0, 2)
+	for len(ids) < 2 && len(data) > 0 {
+		size := int(data[0])
+		if size > 8 || len(data) < 1+size {
+			return 0, 0
+		}
+		var id uint64
+		for i := size - 1; i >= 0; i-- {
+			id = id<<8 | uint64(data[1+i])
+		}
+		ids = append(ids, int(id))
+		data = data[1+size:]
+	}
+	if len(ids) != 2 {
+		return 0, 0
+	}
+	return ids[0], ids[1]
+}
@@ -0,0 +1,130 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarHeaderInfo(t *testing.T) {
+	modTime := time.Unix(1700000000, 123456789)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, header := range []*tar.Header{
+		{Name: "bin/tool", Typeflag: tar.TypeReg, Mode: 04755, Uid: 1000, Gid: 100, Uname: "builder", Gname: "users",
+			ModTime: modTime, AccessTime: modTime.Add(time.Second), ChangeTime: modTime.Add(2 * time.Second), Format: tar.FormatPAX},
+		{Name: "bin/tool-link", Typeflag: tar.TypeLink, Linkname: "bin/tool", Mode: 0755},
+		{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0666, Devmajor: 1, Devminor: 3},
+		{Name: "run/pipe", Typeflag: tar.TypeFifo, Mode: 0600},
+	} {
+		require.NoError(t, tw.WriteHeader(header))
+	}
+	require.NoError(t, tw.Close())
+	path := filepath.Join(t.TempDir(), "info.tar")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	headers := collect(t, TarArchiver{}, path).byPath()
+	tool := headers["bin/tool"]
+	require.NotNil(t, tool)
+	assert.Equal(t, EntryRegular, tool.Type)
+	assert.Equal(t, fs.FileMode(0755)|fs.ModeSetuid, tool.Mode)
+	assert.Equal(t, 1000, tool.Uid)
+	assert.Equal(t, 100, tool.Gid)
+	assert.Equal(t, "builder", tool.Uname)
+	assert.Equal(t, "users", tool.Gname)
+	assert.Equal(t, modTime.UnixNano(), tool.ModTimeNs)
+	assert.Equal(t, modTime.Add(time.Second).UnixNano(), tool.AccessTimeNs)
+	assert.Equal(t, modTime.Add(2*time.Second).UnixNano(), tool.ChangeTimeNs)
+
+	link := headers["bin/tool-link"]
+	require.NotNil(t, link)
+	assert.Equal(t, EntryHardlink, link.Type)
+	assert.Equal(t, "bin/tool", link.LinkTarget)
+	assert.Equal(t, EntryCharDevice, headers["dev/null"].Type)
+	assert.Equal(t, EntryFifo, headers["run/pipe"].Type)
+}
+
+func TestZipHeaderInfo(t *testing.T) {
+	headers := collect(t, ZipArchiver{}, "./fixtures/testwithcontent.zip").byPath()
+	header := headers["test.txt"]
+	require.NotNil(t, header)
+	assert.Equal(t, EntryRegular, header.Type)
+	assert.Equal(t, fs.FileMode(0644), header.Mode)
+	assert.Equal(t, 502, header.Uid)
+	assert.Equal(t, 20, header.Gid)
+	assert.NotZero(t, header.ModTimeNs)
+
+	path := writeTestFile(t, "test.zip", zipBytes(t, symlinkEntry("link", "target.txt")))
+	headers = collect(t, ZipArchiver{}, path).byPath()
+	link := headers["link"]
+	require.NotNil(t, link)
+	assert.Equal(t, EntrySymlink, link.Type)
+	assert.Equal(t, "target.txt", link.LinkTarget)
+	content, err := io.ReadAll(link.ArchiveReader)
+	require.NoError(t, err)
+	assert.Equal(t, "target.txt", string(content), "the content of the symlink can still be read")
+}
+
+func TestRpmHeaderInfo(t *testing.T) {
+	headers := collect(t, RpmArchiver{}, "./fixtures/test.rpm").byPath()
+	header := headers["./usr/share/doc/php-zstd-devel/tests/info.phpt"]
+	require.NotNil(t, header)
+	assert.Equal(t, EntryRegular, header.Type)
+	assert.Equal(t, fs.FileMode(0644), header.Mode)
+	assert.Equal(t, "root", header.Uname)
+	assert.Equal(t, "root", header.Gname)
+	assert.Equal(t, int64(1517299253)*int64(time.Second), header.ModTimeNs)
+}
+
+func TestDebHeaderInfo(t *testing.T) {
+	headers := collect(t, DebArchiver{}, "./fixtures/test.deb").byPath()
+	header := headers["debian-binary"]
+	require.NotNil(t, header)
+	assert.Equal(t, EntryRegular, header.Type)
+	assert.Equal(t, fs.FileMode(0644), header.Mode)
+	assert.Equal(t, int64(1485714631)*int64(time.Second), header.ModTimeNs)
+}
+
+func TestSevenZipAndRarHeaderInfo(t *testing.T) {
+	for _, test := range []struct {
+		archiver Archiver
+		path     string
+	}{
+		{SevenZipArchiver{}, "./fixtures/testwithcontent.7z"},
+		{RarArchiver{}, "./fixtures/testwithcontent.rar"},
+	} {
+		header := collect(t, test.archiver, test.path).byPath()["compression.go"]
+		require.NotNil(t, header, test.path)
+		assert.Equal(t, EntryRegular, header.Type, test.path)
+		assert.Equal(t, fs.FileMode(0644), header.Mode, test.path)
+		assert.Equal(t, int64(1626795275)*int64(time.Second), header.ModTimeNs, test.path)
+	}
+}
+
+func TestUnixModeToFileMode(t *testing.T) {
+	assert.Equal(t, fs.FileMode(0644), unixModeToFileMode(0100644))
+	assert.Equal(t, fs.ModeDir|0755, unixModeToFileMode(040755))
+	assert.Equal(t, fs.ModeSymlink|0777, unixModeToFileMode(0120777))
+	assert.Equal(t, fs.ModeDevice|fs.ModeCharDevice|0666, unixModeToFileMode(020666))
+	assert.Equal(t, fs.ModeSetuid|0755, unixModeToFileMode(0104755))
+}
+
+func TestZipOwner(t *testing.T) {
+	extra := []byte{0x75, 0x78, 11, 0, 1, 4, 0xe8, 0x03, 0, 0, 4, 0x64, 0, 0, 0}
+	uid, gid := zipOwner(append([]byte{0x55, 0x54, 1, 0, 0}, extra...))
+	assert.Equal(t, 1000, uid)
+	assert.Equal(t, 100, gid)
+	uid, gid = zipOwner([]byte{0x75, 0x78, 2, 0, 1})
+	assert.Zero(t, uid)
+	assert.Zero(t, gid)
+}
@@ -0,0 +1,347 @@
+package archive_extractor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/jfrog/go-archive-extractor/compression"
+)
+
+var ErrUnknownFormat = archiver_errors.WithKind(archiver_errors.ErrNotThisFormat, errors.New("archive format could not be identified"))
+
+// Format is the name of an archive format
+type Format string
+
+const (
+	FormatUnknown  Format = ""
+	FormatZip      Format = "zip"
+	FormatTar      Format = "tar"
+	FormatDeb      Format = "deb"
+	FormatRpm      Format = "rpm"
+	FormatSevenZip Format = "7z"
+	FormatRar      Format = "rar"
+	// FormatCompressed is a single compressed file which is not an archive
+	FormatCompressed Format = "compressed"
+)
+
+// Confidence tells how reliable an identification is
+type Confidence int
+
+const (
+	ConfidenceNone Confidence = iota
+	// ConfidenceLow means the format was guessed from the file extension only
+	ConfidenceLow
+	// ConfidenceMedium means the content matches the format, but not at its usual location or not as a whole
+	ConfidenceMedium
+	// ConfidenceHigh means the format was identified by its magic bytes
+	ConfidenceHigh
+)
+
+type Identification struct {
+	Format      Format
+	Compression compression.Format
+	Confidence  Confidence
+	// Archiver is created with the limits given to Identify
+	Archiver Archiver
+}
+
+// ArchiverConfig holds the limits of the archivers created by Identify, and the settings of ExtractToDir
+type ArchiverConfig struct {
+	MaxCompressRatio   int64
+	MaxNumberOfEntries int
+	EntryOptions
+	// Umask is cleared from the modes of the files and folders written by ExtractToDir, DefaultUmask by default
+	Umask fs.FileMode
+	// Symlinks makes ExtractToDir create the symlinks of the archive, they are skipped by default
+	Symlinks bool
+	// Password and PasswordProvider decrypt the encrypted zip, 7z and rar archives
+	Password         string
+	PasswordProvider PasswordProvider
+	// HardlinkAliases reports the tar hardlinks without the content of their target, see TarArchiver
+	HardlinkAliases bool
+	// MaxSpoolSize bounds the temporary file of the tar, 7z and rar archivers holding the link targets, see TarArchiver
+	MaxSpoolSize int64
+	// Workers is the number of zip entries processed concurrently, see ZipArchiver. The other formats ignore it.
+	Workers int
+}
+
+type Option func(*ArchiverConfig)
+
+func WithMaxCompressRatio(maxCompressRatio int64) Option {
+	return func(c *ArchiverConfig) {
+		c.MaxCompressRatio = maxCompressRatio
+	}
+}
+
+func WithMaxNumberOfEntries(maxNumberOfEntries int) Option {
+	return func(c *ArchiverConfig) {
+		c.MaxNumberOfEntries = maxNumberOfEntries
+	}
+}
+
+func WithMaxEntrySize(maxEntrySize int64) Option {
+	return func(c *ArchiverConfig) {
+		c.MaxEntrySize = maxEntrySize
+	}
+}
+
+func WithMaxEntryCompressRatio(maxEntryCompressRatio int64) Option {
+	return func(c *ArchiverConfig) {
+		c.MaxEntryCompressRatio = maxEntryCompressRatio
+	}
+}
+
+func WithDigests(digests ...DigestAlgorithm) Option {
+	return func(c *ArchiverConfig) {
+		c.Digests = digests
+	}
+}
+
+func WithPassword(password string) Option {
+	return func(c *ArchiverConfig) {
+		c.Password = password
+	}
+}
+
+func WithPasswordProvider(passwordProvider PasswordProvider) Option {
+	return func(c *ArchiverConfig) {
+		c.PasswordProvider = passwordProvider
+	}
+}
+
+func WithHardlinkAliases(hardlinkAliases bool) Option {
+	return func(c *ArchiverConfig) {
+		c.HardlinkAliases = hardlinkAliases
+	}
+}
+
+func WithMaxSpoolSize(maxSpoolSize int64) Option {
+	return func(c *ArchiverConfig) {
+		c.MaxSpoolSize = maxSpoolSize
+	}
+}
+
+func WithWorkers(workers int) Option {
+	return func(c *ArchiverConfig) {
+		c.Workers = workers
+	}
+}
+
+// WithInclude adds doublestar patterns to Filter.Include
+func WithInclude(patterns ...string) Option {
+	return func(c *ArchiverConfig) {
+		c.Filter.Include = append(c.Filter.Include, patterns...)
+	}
+}
+
+// WithExclude adds doublestar patterns to Filter.Exclude
+func WithExclude(patterns ...string) Option {
+	return func(c *ArchiverConfig) {
+		c.Filter.Exclude = append(c.Filter.Exclude, patterns...)
+	}
+}
+
+func WithPredicate(predicate func(*ArchiveHeader) bool) Option {
+	return func(c *ArchiverConfig) {
+		c.Filter.Predicate = predicate
+	}
+}
+
+func WithFolders(folders FolderReporting) Option {
+	return func(c *ArchiverConfig) {
+		c.Folders = folders
+	}
+}
+
+func WithNames(names NameNormalization) Option {
+	return func(c *ArchiverConfig) {
+		c.Names = names
+	}
+}
+
+func WithUmask(umask fs.FileMode) Option {
+	return func(c *ArchiverConfig) {
+		c.Umask = umask
+	}
+}
+
+func WithSymlinks(symlinks bool) Option {
+	return func(c *ArchiverConfig) {
+		c.Symlinks = symlinks
+	}
+}
+
+func newArchiverConfig(options []Option) *ArchiverConfig {
+	config := &ArchiverConfig{Umask: DefaultUmask}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+const (
+	headerSniffLen   = 512
+	streamSniffLen   = 4096 // compressed streams are sniffed through their decompression, so more than headerSniffLen is read
+	tarMagicOffset   = 257
+	zipEndSearchSize = 64*1024 + 22 // max comment length + end of central directory record
+)
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	debMagic      = []byte("!<arch>\ndebian-binary")
+	rpmMagic      = []byte{0xED, 0xAB, 0xEE, 0xDB}
+	sevenZipMagic = []byte("7z\xBC\xAF\x27\x1C")
+	rarMagic      = []byte("Rar!\x1A\x07")
+	tarMagic      = []byte("ustar")
+	zipExtensions = []string{".zip", ".jar", ".war", ".ear", ".apk", ".aar", ".whl", ".nupkg", ".egg", ".vsix"}
+)
+
+// Identify detects the format of the archive stored in path by its magic bytes, looking through
+// its compression if there is one, and falls back to the file extension so that misnamed files are identified by their content.
+// Formats added by RegisterFormat are identified as well.
+// ErrUnknownFormat is returned if neither the content nor the extension are recognized.
+func Identify(path string, options ...Option) (*Identification, error) {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return IdentifySource(source, options...)
+}
+
+// IdentifySource works like Identify on a source. The content of a stream source is buffered while it is
+// identified, so the source can still be extracted afterwards.
+// The end of a stream source is not available, so zip files with prepended data are identified by their extension only.
+func IdentifySource(source *Source, options ...Option) (*Identification, error) {
+	config := newArchiverConfig(options)
+	identification, err := identifySource(source)
+	if err != nil {
+		return nil, err
+	}
+	registration, ok := lookupFormat(identification.Format)
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	identification.Archiver = registration.NewArchiver(*config)
+	return identification, nil
+}
+
+// Extract identifies the format of the archive stored in path and extracts it with the matching archiver
+func Extract(path string, processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}, options ...Option) error {
+	return ExtractContext(context.Background(), path, processingFunc, params, options...)
+}
+
+func ExtractContext(ctx context.Context, path string, processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}, options ...Option) error {
+	identification, err := Identify(path, options...)
+	if err != nil {
+		return err
+	}
+	return identification.Archiver.ExtractArchiveContext(ctx, path, processingFunc, params)
+}
+
+func identifySource(source *Source) (*Identification, error) {
+	var head []byte
+	var err error
+	var openDecompressed func() (io.ReadCloser, error)
+	if source.readerAt != nil {
+		head, err = readHead(source.stream())
+		if err != nil {
+			return nil, err
+		}
+		openDecompressed = func() (io.ReadCloser, error) {
+			cReader, _, err := compression.NewReaderFrom(source.stream(), source.Name)
+			return cReader, err
+		}
+	} else {
+		// the stream is replaced by a buffered one, so the sniffed bytes are not lost
+		bufReader := bufio.NewReaderSize(source.reader, streamSniffLen)
+		source.reader = bufReader
+		head, err = bufReader.Peek(streamSniffLen)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		openDecompressed = func() (io.ReadCloser, error) {
+			cReader, _, err := compression.NewReaderFrom(bytes.NewReader(head), source.Name)
+			return cReader, err
+		}
+	}
+	if format := matchFormat(head, false); format != FormatUnknown {
+		return &Identification{Format: format, Confidence: ConfidenceHigh}, nil
+	}
+	if source.readerAt != nil {
+		// prepended data (e.g. self-extracting archives) moves the zip magic, but the end of central directory is still at the end
+		tail, err := readTail(source.readerAt, source.Size)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(tail, zipEmptyMagic) {
+			return &Identification{Format: FormatZip, Confidence: ConfidenceMedium}, nil
+		}
+	}
+	// the compressions are detected by their magic bytes, the extension is only used for the ones without magic bytes
+	if compressionFormat := compression.DetectFormat(source.Name, head); compressionFormat != compression.None {
+		return identifyCompressed(source.Name, compressionFormat, openDecompressed)
+	}
+	if format := matchExtension(source.Name, false); format != FormatUnknown {
+		return &Identification{Format: format, Confidence: ConfidenceLow}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+func identifyCompressed(name string, compressionFormat compression.Format, openDecompressed func() (io.ReadCloser, error)) (*Identification, error) {
+	cReader, err := openDecompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer cReader.Close()
+	head, err := readHead(cReader)
+	if err != nil && len(head) == 0 {
+		// a corrupted stream is still reported as compressed, the archiver will report the actual error
+		return &Identification{Format: FormatCompressed, Compression: compressionFormat, Confidence: ConfidenceLow}, nil
+	}
+	if format := matchFormat(head, true); format != FormatUnknown {
+		return &Identification{Format: format, Compression: compressionFormat, Confidence: ConfidenceHigh}, nil
+	}
+	// the compression extension is removed to find the extension of the archive itself (e.g. .tar.gz)
+	for _, name := range []string{name, strings.TrimSuffix(name, filepath.Ext(name))} {
+		if format := matchExtension(name, true); format != FormatUnknown {
+			return &Identification{Format: format, Compression: compressionFormat, Confidence: ConfidenceLow}, nil
+		}
+	}
+	confidence := ConfidenceLow
+	if compression.HasMagicBytes(compressionFormat) {
+		confidence = ConfidenceHigh
+	}
+	return &Identification{Format: FormatCompressed, Compression: compressionFormat, Confidence: confidence}, nil
+}
+
+// readHead reads the beginning of r, the error is returned together with the bytes that could be read
+func readHead(r io.Reader) ([]byte, error) {
+	head := make([]byte, headerSniffLen)
+	n, err := io.ReadFull(r, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return head[:n], err
+}
+
+func readTail(r io.ReaderAt, size int64) ([]byte, error) {
+	offset := size - zipEndSearchSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, size-offset)
+	n, err := r.ReadAt(tail, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return tail[:n], nil
+}
@@ -0,0 +1,118 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jfrog/go-archive-extractor/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentify(t *testing.T) {
+	var testCases = []struct {
+		FilePath            string
+		ExpectedFormat      Format
+		ExpectedCompression compression.Format
+		ExpectedConfidence  Confidence
+		ExpectedArchiver    Archiver
+	}{
+		{"./fixtures/test.zip", FormatZip, compression.None, ConfidenceHigh, ZipArchiver{}},
+		{"./fixtures/appendedZip", FormatZip, compression.None, ConfidenceMedium, ZipArchiver{}},
+		{"./fixtures/notRarFile.rar", FormatZip, compression.None, ConfidenceHigh, ZipArchiver{}},
+		{"./fixtures/test.tar.gz", FormatTar, compression.Gzip, ConfidenceHigh, TarArchiver{}},
+		{"./fixtures/junit.tar.lzma", FormatTar, compression.Lzma, ConfidenceHigh, TarArchiver{}},
+		{"./fixtures/archive.tar.lz", FormatTar, compression.Lzip, ConfidenceHigh, TarArchiver{}},
+		{"./fixtures/test.deb", FormatDeb, compression.None, ConfidenceHigh, DebArchiver{}},
+		{"./fixtures/test.rpm", FormatRpm, compression.None, ConfidenceHigh, RpmArchiver{}},
+		{"./fixtures/test.7z", FormatSevenZip, compression.None, ConfidenceHigh, SevenZipArchiver{}},
+		{"./fixtures/test.rar", FormatRar, compression.None, ConfidenceHigh, RarArchiver{}},
+		{"./fixtures/test.txt.xz", FormatCompressed, compression.Xz, ConfidenceHigh, Decompressor{}},
+		{"./fixtures/test.txt.zst", FormatCompressed, compression.Zstd, ConfidenceHigh, Decompressor{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.FilePath, func(t *testing.T) {
+			identification, err := Identify(tc.FilePath)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedFormat, identification.Format)
+			assert.Equal(t, tc.ExpectedCompression, identification.Compression)
+			assert.Equal(t, tc.ExpectedConfidence, identification.Confidence)
+			assert.Equal(t, tc.ExpectedArchiver, identification.Archiver)
+		})
+	}
+}
+
+func TestIdentifyMisnamed(t *testing.T) {
+	zipContent := zipBytes(t, testEntry{name: "a.txt", content: []byte("zip content")})
+	tarContent := tarBytes(t, testEntry{name: "a.txt", content: []byte("tar content")})
+	var testCases = []struct {
+		Name             string
+		Content          []byte
+		ExpectedFormat   Format
+		ExpectedArchiver Archiver
+		ExpectedContent  string
+	}{
+		{"zip.gz", zipContent, FormatZip, ZipArchiver{}, "zip content"},
+		{"zip.xz", zipContent, FormatZip, ZipArchiver{}, "zip content"},
+		{"tar.gz", tarContent, FormatTar, TarArchiver{}, "tar content"},
+		{"tar.xz", tarContent, FormatTar, TarArchiver{}, "tar content"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.Name)
+			require.NoError(t, os.WriteFile(path, tc.Content, 0644))
+			identification, err := Identify(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedFormat, identification.Format)
+			assert.Equal(t, compression.None, identification.Compression)
+			assert.Equal(t, ConfidenceHigh, identification.Confidence)
+			assert.Equal(t, tc.ExpectedArchiver, identification.Archiver)
+			assert.Equal(t, map[string]string{"a.txt": tc.ExpectedContent}, collect(t, identification.Archiver, path).contents())
+		})
+	}
+}
+
+func TestIdentifyUnknownFormat(t *testing.T) {
+	_, err := Identify("./fixtures/test.txt")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = Identify("./fixtures/testzoneinfo.zi")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	// an ar archive is a deb package only when its first member is debian-binary
+	ar := []byte("!<arch>\nfoo.o/          0           0     0     644     4         `\nabcd")
+	_, err = Identify(writeTestFile(t, "libfoo.a", ar))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	identification, err := Identify(writeTestFile(t, "foo.deb", ar))
+	require.NoError(t, err)
+	assert.Equal(t, FormatDeb, identification.Format)
+	assert.Equal(t, ConfidenceLow, identification.Confidence)
+}
+
+func TestIdentifyWithLimits(t *testing.T) {
+	identification, err := Identify("./fixtures/test.zip", WithMaxCompressRatio(10), WithMaxNumberOfEntries(100))
+	require.NoError(t, err)
+	assert.Equal(t, ZipArchiver{MaxCompressRatio: 10, MaxNumberOfEntries: 100}, identification.Archiver)
+}
+
+func TestIdentifyWithEntryOptions(t *testing.T) {
+	identification, err := Identify("./fixtures/test.tar.gz", WithMaxEntrySize(10), WithDigests(DigestSHA256), WithMaxSpoolSize(1024))
+	require.NoError(t, err)
+	assert.Equal(t, TarArchiver{EntryOptions: EntryOptions{MaxEntrySize: 10, Digests: []DigestAlgorithm{DigestSHA256}}, MaxSpoolSize: 1024},
+		identification.Archiver)
+}
+
+func TestExtract(t *testing.T) {
+	funcParams := params()
+	err := Extract("./fixtures/test.tar.gz", processingFunc, funcParams)
+	require.NoError(t, err)
+	ad := funcParams["archiveData"].(*ArchiveData)
+	assert.Equal(t, "logRotator-1.0/log_rotator.go", ad.Name)
+}
+
+func TestExtractWithLimits(t *testing.T) {
+	err := Extract("./fixtures/testsinglelarge.tar.gz", processingReadingFunc, params(), WithMaxCompressRatio(2))
+	assert.True(t, IsErrCompressLimitReached(err))
+}
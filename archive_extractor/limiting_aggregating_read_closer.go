@@ -4,9 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sync/atomic"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 )
 
-var ErrTooManyEntries = errors.New("too many entries in archive")
+var ErrTooManyEntries = archiver_errors.WithKind(archiver_errors.ErrLimitExceeded, errors.New("too many entries in archive"))
 
 type ErrCompressLimitReached struct {
 	SizeLimit int64
@@ -27,7 +31,39 @@ func (ErrCompressLimit *ErrCompressLimitReached) Error() string {
 	return fmt.Sprintf("total bytes limit reached with the following values: size limit: %d, total current size: %d", ErrCompressLimit.SizeLimit, ErrCompressLimit.CurrSize)
 }
 
+func (ErrCompressLimit *ErrCompressLimitReached) Is(target error) bool {
+	return target == archiver_errors.ErrLimitExceeded
+}
+
+// ErrEntryLimitReached is returned when a single entry is larger than MaxEntrySize, or expands more than MaxEntryCompressRatio.
+// The size declared by the archive is checked before the entry is read, the bytes actually read are checked while it is read.
+type ErrEntryLimitReached struct {
+	Name      string
+	SizeLimit int64
+	CurrSize  int64
+}
+
+func newErrEntryLimitReached(name string, sizeLimit, size int64) *ErrEntryLimitReached {
+	return &ErrEntryLimitReached{Name: name, SizeLimit: sizeLimit, CurrSize: size}
+}
+
+func IsErrEntryLimitReached(err error) bool {
+	var errEntryLimitReached *ErrEntryLimitReached
+	return errors.As(err, &errEntryLimitReached)
+}
+
+func (e *ErrEntryLimitReached) Error() string {
+	return fmt.Sprintf("entry bytes limit reached for %s with the following values: size limit: %d, entry current size: %d", e.Name, e.SizeLimit, e.CurrSize)
+}
+
+func (e *ErrEntryLimitReached) Is(target error) bool {
+	return target == archiver_errors.ErrLimitExceeded
+}
+
+// LimitAggregatingReadCloserProvider limits the bytes read from all the readers it creates together,
+// they may be read concurrently
 type LimitAggregatingReadCloserProvider struct {
+	// Total is updated atomically
 	Total int64
 	Limit int64
 }
@@ -52,16 +88,15 @@ type limitAggregatingReadCloser struct {
 }
 
 func (crc *limitAggregatingReadCloser) Read(p []byte) (int, error) {
-	if crc.Limit != 0 && *crc.Total > crc.Limit {
-		return 0, newErrCompressLimitReached(crc.Limit, *crc.Total)
+	if total := atomic.LoadInt64(crc.Total); crc.Limit != 0 && total > crc.Limit {
+		return 0, newErrCompressLimitReached(crc.Limit, total)
 	}
 	n, err := crc.Reader.Read(p)
 	if err != nil && err != io.EOF {
 		return n, err
 	}
-	*crc.Total += int64(n)
-	if crc.Limit != 0 && *crc.Total > crc.Limit {
-		return n, newErrCompressLimitReached(crc.Limit, *crc.Total)
+	if total := atomic.AddInt64(crc.Total, int64(n)); crc.Limit != 0 && total > crc.Limit {
+		return n, newErrCompressLimitReached(crc.Limit, total)
 	}
 	return n, err
 }
@@ -73,3 +108,54 @@ func (crc *limitAggregatingReadCloser) Close() error {
 	}
 	return nil
 }
+
+// entryLimits are the limits of every entry on its own, 0 means no limit
+type entryLimits struct {
+	maxSize          int64
+	maxCompressRatio int64
+}
+
+// limit returns the number of bytes the entry may expand to, the ratio is only applied when the compressed size is known
+func (el entryLimits) limit(header *ArchiveHeader) int64 {
+	limit := el.maxSize
+	if el.maxCompressRatio > 0 && header.CompressedSize > 0 && header.CompressedSize <= math.MaxInt64/el.maxCompressRatio {
+		if ratioLimit := header.CompressedSize * el.maxCompressRatio; limit == 0 || ratioLimit < limit {
+			limit = ratioLimit
+		}
+	}
+	return limit
+}
+
+func (el entryLimits) apply(processEntry processEntryFunc) processEntryFunc {
+	if el == (entryLimits{}) {
+		return processEntry
+	}
+	return func(header *ArchiveHeader) error {
+		limit := el.limit(header)
+		if limit == 0 {
+			return processEntry(header)
+		}
+		if header.Size > limit {
+			return newErrEntryLimitReached(header.Path, limit, header.Size)
+		}
+		header.ArchiveReader = &limitEntryReader{reader: header.ArchiveReader, name: header.Path, limit: limit}
+		return processEntry(header)
+	}
+}
+
+// limitEntryReader fails once more than limit bytes are read, the declared size of the entry can't be trusted
+type limitEntryReader struct {
+	reader io.Reader
+	name   string
+	limit  int64
+	total  int64
+}
+
+func (ler *limitEntryReader) Read(p []byte) (int, error) {
+	n, err := ler.reader.Read(p)
+	ler.total += int64(n)
+	if ler.total > ler.limit {
+		return n, newErrEntryLimitReached(ler.name, ler.limit, ler.total)
+	}
+	return n, err
+}
@@ -3,8 +3,11 @@
 package archive_extractor
 
 import (
+	"bytes"
 	"crypto/rand"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
 	"testing"
 )
 
@@ -51,3 +54,44 @@ func TestLimitingReaderErrorWhenAggregatingMultipleReadersFromSameProvider(t *te
 	_, err := reader.Read(b)
 	assert.True(t, IsErrCompressLimitReached(err))
 }
+
+func TestEntryLimitsDeclaredSize(t *testing.T) {
+	limits := entryLimits{maxSize: 100}
+	processEntry := limits.apply(func(header *ArchiveHeader) error {
+		t.Fatal("the entry should be rejected before it is processed")
+		return nil
+	})
+	err := processEntry(NewArchiveHeader(rand.Reader, "big.bin", 0, 150))
+	var entryErr *ErrEntryLimitReached
+	require.ErrorAs(t, err, &entryErr)
+	assert.Equal(t, ErrEntryLimitReached{Name: "big.bin", SizeLimit: 100, CurrSize: 150}, *entryErr)
+}
+
+func TestEntryLimitsBytesRead(t *testing.T) {
+	limits := entryLimits{maxCompressRatio: 10}
+	processEntry := limits.apply(func(header *ArchiveHeader) error {
+		_, err := io.Copy(io.Discard, header.ArchiveReader)
+		return err
+	})
+	// the declared size is within the limit but more bytes are read
+	header := NewArchiveHeader(rand.Reader, "lying.bin", 0, 10)
+	header.CompressedSize = 5
+	err := processEntry(header)
+	assert.True(t, IsErrEntryLimitReached(err))
+	assert.Contains(t, err.Error(), "lying.bin")
+
+	// without a compressed size the ratio can't be applied
+	processEntry = limits.apply(func(header *ArchiveHeader) error {
+		_, err := io.Copy(io.Discard, header.ArchiveReader)
+		return err
+	})
+	assert.NoError(t, processEntry(NewArchiveHeader(bytes.NewReader(make([]byte, 1000)), "unknown.bin", 0, 1000)))
+}
+
+func TestEntryLimitsMinimum(t *testing.T) {
+	header := &ArchiveHeader{CompressedSize: 10}
+	assert.Equal(t, int64(50), entryLimits{maxSize: 50, maxCompressRatio: 10}.limit(header))
+	assert.Equal(t, int64(100), entryLimits{maxSize: 500, maxCompressRatio: 10}.limit(header))
+	assert.Equal(t, int64(500), entryLimits{maxSize: 500}.limit(header))
+	assert.Equal(t, int64(0), entryLimits{maxCompressRatio: 10}.limit(&ArchiveHeader{}))
+}
@@ -0,0 +1,398 @@
+package archive_extractor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/jfrog/go-archive-extractor/utils"
+)
+
+// DefaultMaxSpoolSize is the size of the content of the regular files kept until the links of the archive are all known
+const DefaultMaxSpoolSize int64 = 256 << 20
+
+// linkExtraction reports the links of an archive as the content of their target under the path of the link.
+// The entries are processed as they are read, except for the links which are processed once the whole archive
+// is read and they are all known. The content processEntry reads from the regular files is spooled to a temporary
+// file of at most maxSpoolSize bytes so it can be read again for their links, the link targets that aren't in it
+// are read again from the archive. The symlinks to folders and the ones that lead to no regular file are reported as is once
+// the archive is read, the files under a symlink to a folder are also reported under the path of the symlink.
+type linkExtraction struct {
+	hardlinkAliases bool
+	maxSpoolSize    int64
+	provider        *LimitAggregatingReadCloserProvider
+	processEntry    processEntryFunc
+	links           *archiveLinks
+	// files and symlinks are the headers of the regular files and of the symlinks by their path, as they were
+	// before being processed. The links are reported with their details.
+	files           map[string]ArchiveHeader
+	symlinks        map[string]ArchiveHeader
+	symlinkContents map[string][]byte
+	spool           *os.File
+	spoolSize       int64
+	// spooled are the offsets of the content of the regular files in the spool, by their path
+	spooled map[string]int64
+	// filter selects the regular files before they are opened, and the links once they are resolved,
+	// it matches the names normalised as names sets them
+	filter EntryFilter
+	names  NameNormalization
+	// mutex guards the links and the headers, the entries of a zip archive may be processed concurrently
+	mutex sync.Mutex
+}
+
+// newLinkExtraction creates a link extraction, maxSpoolSize is DefaultMaxSpoolSize when 0 and no spool is used when negative.
+// With hardlinkAliases, the hardlinks to a file are reported as a single entry without content, see TarArchiver.HardlinkAliases.
+func newLinkExtraction(hardlinkAliases bool, maxSpoolSize int64, options EntryOptions, provider *LimitAggregatingReadCloserProvider, processEntry processEntryFunc) *linkExtraction {
+	if maxSpoolSize == 0 {
+		maxSpoolSize = DefaultMaxSpoolSize
+	}
+	return &linkExtraction{
+		hardlinkAliases: hardlinkAliases,
+		maxSpoolSize:    maxSpoolSize,
+		filter:          options.Filter,
+		names:           options.Names,
+		provider:        provider,
+		processEntry:    processEntry,
+		links:           newArchiveLinks(),
+		files:           map[string]ArchiveHeader{},
+		symlinks:        map[string]ArchiveHeader{},
+		symlinkContents: map[string][]byte{},
+		spooled:         map[string]int64{},
+	}
+}
+
+// process is the processEntryFunc the archive is read with
+func (le *linkExtraction) process(ctx context.Context) processEntryFunc {
+	return func(header *ArchiveHeader) error {
+		name := cleanArchivePath(header.Name)
+		switch {
+		case header.IsFolder:
+			return le.processEntry(header)
+		case header.Type == EntrySymlink:
+			content, err := io.ReadAll(io.LimitReader(header.ArchiveReader, maxLinkTargetSize))
+			if err != nil {
+				return err
+			}
+			le.mutex.Lock()
+			inRoot := le.links.addSymlink(name, header.LinkTarget)
+			if inRoot {
+				le.symlinks[name] = *header
+				le.symlinkContents[name] = content
+			}
+			le.mutex.Unlock()
+			if !inRoot {
+				// the symlinks pointing outside of the archive can't be resolved, they are reported as is
+				header.OutsideRoot = true
+				header.ArchiveReader = bytes.NewReader(content)
+				return le.processEntry(header)
+			}
+			return nil
+		case header.Type == EntryHardlink:
+			le.mutex.Lock()
+			resolved := le.links.addHardlink(name, header.LinkTarget)
+			le.mutex.Unlock()
+			if resolved {
+				// reported with the content of their target like the symlinks, or together with the other hardlinks to it
+				return nil
+			}
+			return le.processEntry(header)
+		case header.Type == EntryRegular:
+			le.mutex.Lock()
+			le.links.addFile(name)
+			le.files[name] = *header
+			le.mutex.Unlock()
+			return le.processFile(name, header)
+		}
+		return le.processEntry(header)
+	}
+}
+
+// processing is process, stopping at the first encrypted entry when stopAtEncrypted is set (see archivePassword.extract)
+func (le *linkExtraction) processing(ctx context.Context, stopAtEncrypted bool) processEntryFunc {
+	if stopAtEncrypted {
+		return stopAtEncryptedEntry(le.process(ctx))
+	}
+	return le.process(ctx)
+}
+
+// entrySelector tells whether an archiver opens the entry and passes it on, given its header without ArchiveReader
+type entrySelector func(*ArchiveHeader) bool
+
+// selects is the entrySelector the archive is read with. The regular files the filter doesn't select are recorded
+// without being opened, their content is read again from the archive if a link to them is selected.
+func (le *linkExtraction) selects(header *ArchiveHeader) bool {
+	if header.IsFolder || header.Type != EntryRegular {
+		return true
+	}
+	if le.filter.match(header, le.names) {
+		header.selected = true
+		return true
+	}
+	name := cleanArchivePath(header.Name)
+	le.mutex.Lock()
+	defer le.mutex.Unlock()
+	le.links.addFile(name)
+	le.files[name] = *header
+	return false
+}
+
+// spoolWriter writes to the spool until the content exceeds the size it was given
+type spoolWriter struct {
+	writer   *io.OffsetWriter
+	left     int64
+	overflow bool
+}
+
+func (sw *spoolWriter) Write(p []byte) (int, error) {
+	if sw.overflow || int64(len(p)) > sw.left {
+		sw.overflow = true
+		return len(p), nil
+	}
+	sw.left -= int64(len(p))
+	return sw.writer.Write(p)
+}
+
+// processFile processes a regular file, the content processEntry reads is spooled if it fits in the spool.
+// The content it doesn't read is not read for the links that may come, the files it didn't read to their end
+// are read again from the archive if they turn out to be link targets.
+func (le *linkExtraction) processFile(name string, header *ArchiveHeader) error {
+	offset, err := le.reserveSpool(header.Size)
+	if err != nil {
+		return err
+	}
+	if offset < 0 {
+		return le.processEntry(header)
+	}
+	writer := &spoolWriter{writer: io.NewOffsetWriter(le.spool, offset), left: header.Size}
+	header.ArchiveReader = io.TeeReader(header.ArchiveReader, writer)
+	err = le.processEntry(header)
+	le.mutex.Lock()
+	defer le.mutex.Unlock()
+	if err == nil && !writer.overflow && writer.left == 0 {
+		le.spooled[name] = offset
+	} else if le.spoolSize == offset+header.Size {
+		// the space of the last file is given back
+		le.spoolSize = offset
+	}
+	return err
+}
+
+// reserveSpool returns the offset of size bytes in the spool, or -1 when they don't fit in it
+func (le *linkExtraction) reserveSpool(size int64) (int64, error) {
+	le.mutex.Lock()
+	defer le.mutex.Unlock()
+	if le.maxSpoolSize < 0 || size < 0 || le.spoolSize+size > le.maxSpoolSize {
+		return -1, nil
+	}
+	if le.spool == nil {
+		var err error
+		if le.spool, err = os.CreateTemp("", "archive-extractor-*"); err != nil {
+			return 0, err
+		}
+	}
+	offset := le.spoolSize
+	le.spoolSize += size
+	return offset, nil
+}
+
+// finish reports the links once the whole archive is read, err being the error of reading it.
+// The MultiError of the entries that couldn't be read is returned once the links are reported.
+// reextract reads the archive again, opening the entries selectEntry selects and passing them to processEntry,
+// for the link targets that aren't spooled.
+func (le *linkExtraction) finish(ctx context.Context, err error, reextract func(selectEntry entrySelector, processEntry processEntryFunc) error) error {
+	defer le.removeSpool()
+	var multiErrors *archiver_errors.MultiError
+	if err != nil && !errors.As(err, &multiErrors) {
+		return err
+	}
+	if expandErr := le.links.expand(); expandErr != nil {
+		return expandErr
+	}
+	missing := map[string][]*ArchiveHeader{}
+	for _, file := range le.links.files {
+		aliases := le.aliasHeaders(file)
+		if len(aliases) == 0 {
+			continue
+		}
+		offset, ok := le.spooled[file]
+		if !ok {
+			missing[file] = aliases
+			continue
+		}
+		for _, alias := range aliases {
+			alias.ArchiveReader = le.counted(ctx, io.NewSectionReader(le.spool, offset, le.files[file].Size))
+			if processErr := le.processEntry(alias); processErr != nil {
+				return processErr
+			}
+		}
+	}
+	if len(missing) > 0 {
+		selectMissing := func(header *ArchiveHeader) bool {
+			_, ok := missing[cleanArchivePath(header.Name)]
+			return ok && !header.IsFolder && header.Type == EntryRegular
+		}
+		reextractErr := reextract(selectMissing, func(header *ArchiveHeader) error {
+			name := cleanArchivePath(header.Name)
+			aliases, ok := missing[name]
+			if !ok || header.IsFolder || header.Type != EntryRegular {
+				return nil
+			}
+			delete(missing, name)
+			if processErr := le.processAliases(ctx, header.ArchiveReader, aliases); processErr != nil {
+				return processErr
+			}
+			if len(missing) == 0 {
+				// the rest of the archive is not needed
+				return fs.SkipAll
+			}
+			return nil
+		})
+		// the entries that can't be read are already reported
+		if reextractErr != nil && !errors.Is(reextractErr, fs.SkipAll) && !errors.As(reextractErr, new(*archiver_errors.MultiError)) {
+			return reextractErr
+		}
+	}
+	if le.hardlinkAliases {
+		if processErr := le.processHardlinkAliases(ctx); processErr != nil {
+			return processErr
+		}
+	}
+	for _, symlink := range le.links.symlinkPaths {
+		if le.links.resolvedSymlinks[symlink] {
+			continue
+		}
+		header := le.symlinks[symlink]
+		header.ArchiveReader = le.counted(ctx, bytes.NewReader(le.symlinkContents[symlink]))
+		if processErr := le.processEntry(&header); processErr != nil {
+			return processErr
+		}
+	}
+	return err
+}
+
+func (le *linkExtraction) removeSpool() {
+	if le.spool != nil {
+		_ = le.spool.Close()
+		_ = os.Remove(le.spool.Name())
+	}
+}
+
+// aliasHeaders are the headers the content of the regular file is reported under besides its own path, except for
+// the ones the filter doesn't select
+func (le *linkExtraction) aliasHeaders(file string) []*ArchiveHeader {
+	aliases := slices.Clone(le.links.symlinks[file])
+	if !le.hardlinkAliases {
+		for _, hardlink := range le.links.hardlinks[file] {
+			aliases = append(aliases, linkAlias{path: hardlink, hardlink: true})
+		}
+	}
+	var headers []*ArchiveHeader
+	for _, alias := range aliases {
+		if header := le.newAliasHeader(file, alias); le.filter.match(header, le.names) {
+			header.selected = true
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// processHardlinkAliases reports the hardlinks to every file as a single entry, except for the ones the filter doesn't select
+func (le *linkExtraction) processHardlinkAliases(ctx context.Context) error {
+	for _, file := range le.links.files {
+		var header *ArchiveHeader
+		for _, hardlink := range le.links.hardlinks[file] {
+			alias := le.newAliasHeader(file, linkAlias{path: hardlink, hardlink: true})
+			if !le.filter.match(alias, le.names) {
+				continue
+			}
+			if header == nil {
+				header = alias
+				header.selected = true
+			}
+			header.Aliases = append(header.Aliases, hardlink)
+		}
+		if header == nil {
+			continue
+		}
+		// there is no content, like in the hardlink entries of the archive
+		header.Size = 0
+		header.CompressedSize = 0
+		header.ArchiveReader = le.counted(ctx, strings.NewReader(""))
+		if err := le.processEntry(header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processAliases reports the content under every alias. When there are several aliases, the content is
+// copied to a temporary file while it is processed under the first one, and read again from it for the others.
+func (le *linkExtraction) processAliases(ctx context.Context, content io.Reader, aliases []*ArchiveHeader) error {
+	aliases[0].ArchiveReader = content
+	if len(aliases) == 1 {
+		return le.processEntry(aliases[0])
+	}
+	spool, err := os.CreateTemp("", "archive-extractor-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+	aliases[0].ArchiveReader = io.TeeReader(content, spool)
+	if err = le.processEntry(aliases[0]); err != nil {
+		return err
+	}
+	if _, err = io.Copy(spool, content); err != nil {
+		return err
+	}
+	for _, alias := range aliases[1:] {
+		alias.ArchiveReader = le.counted(ctx, io.NewSectionReader(spool, 0, math.MaxInt64))
+		if err = le.processEntry(alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (le *linkExtraction) counted(ctx context.Context, content io.Reader) io.Reader {
+	return le.provider.CreateLimitAggregatingReadCloser(utils.NewContextReader(ctx, content))
+}
+
+// newAliasHeader creates the header of a link reported with the content of the regular file. The symlinks keep
+// their own details, the hardlinks are reported with the type EntryHardlink and the path of the file as LinkTarget,
+// and the paths through symlinks to folders with the details of the file.
+func (le *linkExtraction) newAliasHeader(file string, alias linkAlias) *ArchiveHeader {
+	target := le.files[file]
+	header := target
+	if alias.symlink {
+		header = le.symlinks[alias.path]
+		header.Size = target.Size
+		header.CompressedSize = target.CompressedSize
+		header.Encrypted = target.Encrypted
+	}
+	if alias.hardlink {
+		header.Type = EntryHardlink
+		header.LinkTarget = file
+	}
+	if !alias.symlink {
+		// there is no entry of its own in the archive
+		header.RawName = alias.path
+	}
+	header.Name = alias.path
+	header.Path = alias.path
+	header.ViaSymlink = alias.via
+	header.Digests = nil
+	header.ArchiveReader = nil
+	header.selected = false
+	return &header
+}
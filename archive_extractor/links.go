@@ -0,0 +1,156 @@
+package archive_extractor
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/jfrog/go-archive-extractor/utils"
+)
+
+// maxSymlinkPaths caps the number of paths synthesized by expanding the symlinks of an archive
+const maxSymlinkPaths = 100000
+
+// ErrTooManySymlinkPaths is returned when the symlinks of an archive, e.g. symlinks to the folders containing them,
+// make more than maxSymlinkPaths paths reachable
+var ErrTooManySymlinkPaths = archiver_errors.WithKind(archiver_errors.ErrLimitExceeded, errors.New("too many paths reachable through the symlinks of the archive"))
+
+// archiveLinks resolves the links of an archive to the regular files they point to
+type archiveLinks struct {
+	// files are the regular files in the order of the archive
+	files        []string
+	regularFiles map[string]bool
+	// symlinkPaths are the symlinks in the order of the archive, and symlinkTargets their targets relative to the archive root
+	symlinkPaths   []string
+	symlinkTargets map[string]string
+	// resolvedSymlinks are the symlinks leading to a regular file, they are reported with its content
+	resolvedSymlinks map[string]bool
+	// symlinks are the paths the regular files are reachable at through symlinks, by the path of the file
+	symlinks map[string][]linkAlias
+	// hardlinks are the hardlinks to the regular files, by the path of the file
+	hardlinks map[string][]string
+}
+
+// linkAlias is a path the content of a regular file is reported under besides its own path
+type linkAlias struct {
+	path string
+	// symlink is set when the path is a symlink itself, rather than a path through a symlink to a folder
+	symlink  bool
+	hardlink bool
+	// via is the symlink to a folder the path goes through
+	via string
+}
+
+func newArchiveLinks() *archiveLinks {
+	return &archiveLinks{
+		regularFiles:     map[string]bool{},
+		symlinkTargets:   map[string]string{},
+		resolvedSymlinks: map[string]bool{},
+		symlinks:         map[string][]linkAlias{},
+		hardlinks:        map[string][]string{},
+	}
+}
+
+func cleanArchivePath(name string) string {
+	return strings.TrimPrefix(utils.CleanPathKeepingUnixSlash(name), "/")
+}
+
+func (al *archiveLinks) addFile(filePath string) {
+	al.files = append(al.files, filePath)
+	al.regularFiles[filePath] = true
+}
+
+// addHardlink records the hardlink if its target is a regular file already met, as it always is in tar archives.
+// Hardlink targets are relative to the archive root.
+func (al *archiveLinks) addHardlink(linkPath, linkTarget string) bool {
+	target := cleanArchivePath(linkTarget)
+	if !al.regularFiles[target] {
+		return false
+	}
+	al.hardlinks[target] = append(al.hardlinks[target], linkPath)
+	return true
+}
+
+// addSymlink records the target of the symlink relative to the archive root, absolute targets
+// are resolved from the archive root like in container images. It returns false when the target
+// is outside of the archive root.
+func (al *archiveLinks) addSymlink(linkPath, linkTarget string) bool {
+	dir := path.Dir(linkPath)
+	if path.IsAbs(linkTarget) {
+		dir = "/"
+	}
+	target := strings.TrimPrefix(path.Join(dir, linkTarget), "/")
+	if target == ".." || strings.HasPrefix(target, "../") {
+		return false
+	}
+	if target == "" {
+		target = "."
+	}
+	al.symlinkPaths = append(al.symlinkPaths, linkPath)
+	al.symlinkTargets[linkPath] = target
+	return true
+}
+
+// reachablePath is a path a regular file is reachable at
+type reachablePath struct {
+	path string
+	file string
+	// via are the symlinks followed to reach the path, a symlink is followed once per path so that loops end
+	via []string
+}
+
+// expand computes the paths every regular file is reachable at through the symlinks, including the
+// symlinks to the folders containing it, which are followed transitively
+func (al *archiveLinks) expand() error {
+	linksByTarget := map[string][]string{}
+	for _, linkPath := range al.symlinkPaths {
+		target := al.symlinkTargets[linkPath]
+		linksByTarget[target] = append(linksByTarget[target], linkPath)
+	}
+	reachable := map[string]bool{}
+	var queue []reachablePath
+	for _, file := range al.files {
+		reachable[file] = true
+		queue = append(queue, reachablePath{path: file, file: file})
+		for _, hardlink := range al.hardlinks[file] {
+			reachable[hardlink] = true
+			queue = append(queue, reachablePath{path: hardlink, file: file})
+		}
+	}
+	synthesized := 0
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		// the symlinks to the path itself or to one of its folders, "." being the archive root
+		for prefix := current.path; ; prefix = path.Dir(prefix) {
+			suffix := "/" + current.path
+			if prefix != "." {
+				suffix = current.path[len(prefix):]
+			}
+			for _, linkPath := range linksByTarget[prefix] {
+				aliasPath := linkPath + suffix
+				if slices.Contains(current.via, linkPath) || reachable[aliasPath] {
+					continue
+				}
+				if synthesized++; synthesized > maxSymlinkPaths {
+					return ErrTooManySymlinkPaths
+				}
+				reachable[aliasPath] = true
+				alias := linkAlias{path: aliasPath, symlink: true}
+				if suffix != "" {
+					alias = linkAlias{path: aliasPath, via: linkPath}
+				} else {
+					al.resolvedSymlinks[linkPath] = true
+				}
+				al.symlinks[current.file] = append(al.symlinks[current.file], alias)
+				queue = append(queue, reachablePath{path: aliasPath, file: current.file, via: append(slices.Clone(current.via), linkPath)})
+			}
+			if prefix == "." {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarDirectorySymlinks(t *testing.T) {
+	path := writeTestFile(t, "symlinks.tar", tarBytes(t, fileEntry("usr/lib/libfoo.so.1", "foo"),
+		fileEntry("usr/lib/x86_64/libbar.so", "bar"),
+		symlinkEntry("lib", "usr/lib"),
+		symlinkEntry("usr/lib/libfoo.so", "libfoo.so.1"),
+		symlinkEntry("lib64", "/lib/x86_64")))
+	assert.Equal(t, map[string]string{
+		"usr/lib/libfoo.so.1":      "foo",
+		"usr/lib/libfoo.so":        "foo",
+		"lib/libfoo.so.1":          "foo",
+		"lib/libfoo.so":            "foo",
+		"usr/lib/x86_64/libbar.so": "bar",
+		"lib/x86_64/libbar.so":     "bar",
+		"lib64/libbar.so":          "bar",
+		// the symlinks to folders are reported as is
+		"lib":   "",
+		"lib64": "",
+	}, collect(t, TarArchiver{}, path).contents())
+}
+
+func TestTarSymlinkLoops(t *testing.T) {
+	path := writeTestFile(t, "symlinks.tar", tarBytes(t, fileEntry("data/file.txt", "content"),
+		symlinkEntry("data/self", "."),
+		symlinkEntry("data/parent", ".."),
+		symlinkEntry("a", "b"),
+		symlinkEntry("b", "a")))
+	assert.Equal(t, map[string]string{
+		"data/file.txt":             "content",
+		"data/self/file.txt":        "content",
+		"data/parent/data/file.txt": "content",
+		// the loops are only followed once per path
+		"data/parent/data/self/file.txt": "content",
+		"data/self/parent/data/file.txt": "content",
+		// the symlinks to folders and the ones leading to no file are reported as is
+		"data/self":   "",
+		"data/parent": "",
+		"a":           "",
+		"b":           "",
+	}, collect(t, TarArchiver{}, path).contents())
+}
+
+func TestTarSymlinkOutsideRoot(t *testing.T) {
+	path := writeTestFile(t, "symlinks.tar", tarBytes(t, fileEntry("etc/hosts", "hosts"),
+		symlinkEntry("etc/passwd", "../../etc/passwd"),
+		symlinkEntry("hosts", "/etc/hosts")))
+	headers := collect(t, TarArchiver{}, path).byPath()
+	require.Len(t, headers, 3)
+	passwd := headers["etc/passwd"]
+	assert.True(t, passwd.OutsideRoot)
+	assert.Equal(t, EntrySymlink, passwd.Type)
+	assert.Equal(t, "../../etc/passwd", passwd.LinkTarget)
+	// absolute targets are resolved from the archive root
+	assert.False(t, headers["hosts"].OutsideRoot)
+	content, err := io.ReadAll(headers["hosts"].ArchiveReader)
+	require.NoError(t, err)
+	assert.Equal(t, "hosts", string(content))
+}
+
+func TestTarSymlinkPathsLimit(t *testing.T) {
+	// every folder links to the root, so the paths grow exponentially with the depth
+	entries := []testEntry{fileEntry("file.txt", "content")}
+	for i := 0; i < 20; i++ {
+		entries = append(entries, symlinkEntry(fmt.Sprintf("d%d", i), "."))
+	}
+	path := writeTestFile(t, "symlinks.tar", tarBytes(t, entries...))
+	err := TarArchiver{}.ExtractArchive(path, processingFunc, params())
+	assert.ErrorIs(t, err, ErrTooManySymlinkPaths)
+}
+
+func TestTarSymlinkHeaders(t *testing.T) {
+	path := writeTestFile(t, "symlinks.tar", tarBytes(t, fileEntry("usr/lib/libfoo.so.1", "foo"),
+		symlinkEntry("lib", "usr/lib"),
+		symlinkEntry("usr/lib/libfoo.so", "libfoo.so.1")))
+	headers := collect(t, TarArchiver{}, path).byPath()
+	// the symlinks keep their details, the paths through symlinks to folders get the details of the file
+	assert.Equal(t, EntrySymlink, headers["usr/lib/libfoo.so"].Type)
+	assert.Equal(t, "libfoo.so.1", headers["usr/lib/libfoo.so"].LinkTarget)
+	assert.Equal(t, int64(3), headers["usr/lib/libfoo.so"].Size)
+	assert.Equal(t, EntryRegular, headers["lib/libfoo.so.1"].Type)
+	assert.Equal(t, EntryRegular, headers["lib/libfoo.so"].Type)
+	assert.Equal(t, "lib", headers["lib/libfoo.so.1"].ViaSymlink)
+	assert.Empty(t, headers["usr/lib/libfoo.so"].ViaSymlink)
+	assert.Equal(t, EntrySymlink, headers["lib"].Type)
+	assert.Equal(t, "usr/lib", headers["lib"].LinkTarget)
+}
+
+func TestZipSymlinks(t *testing.T) {
+	path := writeTestFile(t, "test.zip", zipBytes(t,
+		symlinkEntry("lib", "usr/lib"),
+		fileEntry("usr/lib/libfoo.so.1", "foo"),
+		symlinkEntry("usr/lib/libfoo.so", "libfoo.so.1"),
+		symlinkEntry("escape", "../etc/passwd"),
+		symlinkEntry("dangling", "missing")))
+	headers := collect(t, ZipArchiver{}, path).byPath()
+	contents := map[string]string{}
+	for name, header := range headers {
+		content, err := io.ReadAll(header.ArchiveReader)
+		require.NoError(t, err)
+		contents[name] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"usr/lib/libfoo.so.1": "foo",
+		"usr/lib/libfoo.so":   "foo",
+		"lib/libfoo.so.1":     "foo",
+		"lib/libfoo.so":       "foo",
+		// the symlinks to folders and the ones which can't be resolved are reported as is
+		"lib":      "usr/lib",
+		"escape":   "../etc/passwd",
+		"dangling": "missing",
+	}, contents)
+	assert.True(t, headers["escape"].OutsideRoot)
+	assert.Equal(t, EntrySymlink, headers["dangling"].Type)
+	assert.Equal(t, "missing", headers["dangling"].LinkTarget)
+	assert.Equal(t, EntrySymlink, headers["usr/lib/libfoo.so"].Type)
+}
+
+func TestSevenZipSymlinks(t *testing.T) {
+	for _, maxSpoolSize := range []int64{0, -1} {
+		headers := collect(t, SevenZipArchiver{MaxSpoolSize: maxSpoolSize}, "./fixtures/testsymlinks.7z").byPath()
+		contents := map[string]string{}
+		for name, header := range headers {
+			if header.IsFolder {
+				continue
+			}
+			content, err := io.ReadAll(header.ArchiveReader)
+			require.NoError(t, err)
+			contents[name] = string(content)
+		}
+		assert.Equal(t, map[string]string{
+			"usr/lib/libfoo.so.1": "foo",
+			"usr/lib/libfoo.so":   "foo",
+			"lib/libfoo.so.1":     "foo",
+			"lib/libfoo.so":       "foo",
+			"lib":                 "usr/lib",
+			"dangling":            "missing",
+		}, contents)
+		assert.Equal(t, EntrySymlink, headers["dangling"].Type)
+	}
+}
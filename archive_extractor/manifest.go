@@ -0,0 +1,94 @@
+package archive_extractor
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/jfrog/go-archive-extractor/compression"
+)
+
+// ArchiveManifest lists the entries of an archive, it can be serialized to JSON
+type ArchiveManifest struct {
+	Format       Format             `json:"format"`
+	Compression  compression.Format `json:"compression,omitempty"`
+	EntriesCount int                `json:"entriesCount"`
+	// TotalSize is the sum of the uncompressed sizes of the entries
+	TotalSize int64           `json:"totalSize"`
+	Entries   []ManifestEntry `json:"entries"`
+}
+
+type ManifestEntry struct {
+	Path string    `json:"path"`
+	Type EntryType `json:"type"`
+	Size int64     `json:"size"`
+	// CompressedSize is 0 when the format doesn't store it
+	CompressedSize int64       `json:"compressedSize,omitempty"`
+	Mode           fs.FileMode `json:"mode,omitempty"`
+	ModTime        time.Time   `json:"modTime"`
+	LinkTarget     string      `json:"linkTarget,omitempty"`
+	Aliases        []string    `json:"aliases,omitempty"`
+	Encrypted      bool        `json:"encrypted,omitempty"`
+	// Digests are computed when requested with WithDigests
+	Digests map[DigestAlgorithm]string `json:"digests,omitempty"`
+}
+
+// Manifest identifies the format of the archive stored in path (see Identify) and lists its entries.
+// MaxCompressRatio and MaxNumberOfEntries are enforced like when extracting, the content of the entries
+// is only read to compute the digests requested with WithDigests.
+func Manifest(path string, options ...Option) (*ArchiveManifest, error) {
+	return ManifestContext(context.Background(), path, options...)
+}
+
+func ManifestContext(ctx context.Context, path string, options ...Option) (*ArchiveManifest, error) {
+	config := newArchiverConfig(options)
+	identification, err := Identify(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &ArchiveManifest{Format: identification.Format, Compression: identification.Compression, Entries: []ManifestEntry{}}
+	_, err = ExtractTyped(ctx, identification.Archiver, path, func(header *ArchiveHeader, manifest *ArchiveManifest) error {
+		if len(config.Digests) > 0 {
+			// the digests are set once the content is read to its end
+			if _, err := io.Copy(io.Discard, header.ArchiveReader); err != nil {
+				return err
+			}
+		}
+		manifest.add(header)
+		return nil
+	}, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (m *ArchiveManifest) add(header *ArchiveHeader) {
+	entryType := header.Type
+	if entryType == EntryRegular {
+		// archivers outside of this package may only set the mode
+		entryType = entryTypeOf(header.Mode)
+	}
+	if header.IsFolder {
+		entryType = EntryDir
+	}
+	modTime := time.Unix(header.ModTime, 0)
+	if header.ModTimeNs != 0 {
+		modTime = time.Unix(0, header.ModTimeNs)
+	}
+	m.Entries = append(m.Entries, ManifestEntry{
+		Path:           header.Path,
+		Type:           entryType,
+		Size:           header.Size,
+		CompressedSize: header.CompressedSize,
+		Mode:           header.Mode,
+		ModTime:        modTime.UTC(),
+		LinkTarget:     header.LinkTarget,
+		Aliases:        header.Aliases,
+		Encrypted:      header.Encrypted,
+		Digests:        header.Digests,
+	})
+	m.EntriesCount++
+	m.TotalSize += header.Size
+}
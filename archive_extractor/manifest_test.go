@@ -0,0 +1,85 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"encoding/json"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jfrog/go-archive-extractor/compression"
+)
+
+func TestManifestZip(t *testing.T) {
+	manifest, err := Manifest("./fixtures/testwithcontent.zip", WithDigests(DigestSHA256))
+	require.NoError(t, err)
+	assert.Equal(t, FormatZip, manifest.Format)
+	assert.Equal(t, compression.None, manifest.Compression)
+	assert.Equal(t, 1, manifest.EntriesCount)
+	assert.Equal(t, int64(13), manifest.TotalSize)
+	require.Len(t, manifest.Entries, 1)
+	entry := manifest.Entries[0]
+	assert.Equal(t, "test.txt", entry.Path)
+	assert.Equal(t, EntryRegular, entry.Type)
+	assert.Equal(t, int64(13), entry.Size)
+	assert.NotZero(t, entry.CompressedSize)
+	assert.Equal(t, fs.FileMode(0644), entry.Mode.Perm())
+	assert.Len(t, entry.Digests[DigestSHA256], 64)
+}
+
+func TestManifestTarGz(t *testing.T) {
+	manifest, err := Manifest("./fixtures/test.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, FormatTar, manifest.Format)
+	assert.Equal(t, compression.Gzip, manifest.Compression)
+	assert.Equal(t, len(manifest.Entries), manifest.EntriesCount)
+	var paths []string
+	var totalSize int64
+	for _, entry := range manifest.Entries {
+		paths = append(paths, entry.Path)
+		totalSize += entry.Size
+		assert.Nil(t, entry.Digests)
+	}
+	assert.Contains(t, paths, "logRotator-1.0/README.md")
+	assert.Contains(t, paths, "logRotator-1.0/log_rotator.go")
+	assert.Equal(t, totalSize, manifest.TotalSize)
+}
+
+func TestManifestJSON(t *testing.T) {
+	content := []byte("manifest content")
+	path := tarGzFile(t, "manifest.tar.gz", testEntry{name: "dir/file.txt", content: content})
+	manifest, err := Manifest(path, WithDigests(DigestSHA256))
+	require.NoError(t, err)
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "tar", decoded["format"])
+	assert.EqualValues(t, 1, decoded["entriesCount"])
+	assert.EqualValues(t, len(content), decoded["totalSize"])
+	entries := decoded["entries"].([]interface{})
+	require.Len(t, entries, 1)
+	entry := entries[0].(map[string]interface{})
+	assert.Equal(t, "dir/file.txt", entry["path"])
+	assert.Equal(t, "regular", entry["type"])
+	assert.Equal(t, map[string]interface{}{"sha256": hexSha256(content)}, entry["digests"])
+}
+
+func TestManifestLimits(t *testing.T) {
+	_, err := Manifest("./fixtures/testwithmanyfiles.zip", WithMaxNumberOfEntries(10))
+	assert.ErrorIs(t, err, ErrTooManyEntries)
+	_, err = Manifest("./fixtures/testwithsinglelargefile.zip", WithMaxCompressRatio(1), WithDigests(DigestMD5))
+	assert.Error(t, err)
+}
+
+func TestManifestEncrypted(t *testing.T) {
+	manifest, err := Manifest("./fixtures/testencrypted.zip")
+	require.NoError(t, err)
+	require.Len(t, manifest.Entries, 2)
+	for _, entry := range manifest.Entries {
+		assert.True(t, entry.Encrypted)
+	}
+}
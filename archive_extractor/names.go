@@ -0,0 +1,48 @@
+package archive_extractor
+
+import (
+	"path"
+	"strings"
+)
+
+// NameNormalization controls how the names of the entries are normalised before being passed to processingFunc,
+// the name stored in the archive is always kept in ArchiveHeader.RawName
+type NameNormalization int
+
+const (
+	// NamesDefault keeps the behaviour every archiver always had: TarArchiver reports the names cleaned
+	// and without their leading slash, the other archivers report them as stored
+	NamesDefault NameNormalization = iota
+	// NamesRaw reports the names as stored in the archive
+	NamesRaw
+	// NamesClean converts the backslashes to slashes and cleans the names like path.Clean, which removes the "./"
+	// elements, collapses the duplicate slashes and resolves the ".." elements. The leading slash is kept.
+	NamesClean
+	// NamesRelative cleans the names like NamesClean, and removes their leading slash
+	NamesRelative
+)
+
+func (nn NameNormalization) normalize(rawName string) string {
+	if nn == NamesRaw {
+		return rawName
+	}
+	name := path.Clean(strings.ReplaceAll(rawName, `\`, "/"))
+	if nn == NamesRelative {
+		if name = strings.TrimLeft(name, "/"); name == "" {
+			name = "."
+		}
+	}
+	return name
+}
+
+// apply sets the names of the entries from their raw name, NamesDefault leaves them as the archiver set them
+func (nn NameNormalization) apply(processEntry processEntryFunc) processEntryFunc {
+	if nn == NamesDefault {
+		return processEntry
+	}
+	return func(header *ArchiveHeader) error {
+		header.Name = nn.normalize(header.RawName)
+		header.Path = header.Name
+		return processEntry(header)
+	}
+}
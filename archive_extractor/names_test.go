@@ -0,0 +1,66 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameNormalize(t *testing.T) {
+	tests := []struct {
+		rawName  string
+		clean    string
+		relative string
+	}{
+		{"usr/bin/tool", "usr/bin/tool", "usr/bin/tool"},
+		{"./usr/bin/tool", "usr/bin/tool", "usr/bin/tool"},
+		{"usr//bin/./tool", "usr/bin/tool", "usr/bin/tool"},
+		{`usr\bin\tool`, "usr/bin/tool", "usr/bin/tool"},
+		{"/usr/bin/tool", "/usr/bin/tool", "usr/bin/tool"},
+		{"usr/lib/../bin/tool", "usr/bin/tool", "usr/bin/tool"},
+		{"../tool", "../tool", "../tool"},
+		{"/", "/", "."},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.rawName, NamesRaw.normalize(test.rawName))
+		assert.Equal(t, test.clean, NamesClean.normalize(test.rawName), test.rawName)
+		assert.Equal(t, test.relative, NamesRelative.normalize(test.rawName), test.rawName)
+	}
+}
+
+func TestZipArchiverNames(t *testing.T) {
+	path := writeTestFile(t, "test.zip", zipBytes(t,
+		fileEntry("./a//b.txt", "b"),
+		testEntry{name: `dir\c.txt`, content: []byte("c"), mode: 0644},
+		fileEntry("/abs/d.txt", "d")))
+	assert.ElementsMatch(t, []string{"./a//b.txt", `dir\c.txt`, "/abs/d.txt"}, collect(t, ZipArchiver{}, path).names())
+	assert.ElementsMatch(t, []string{"a/b.txt", "dir/c.txt", "/abs/d.txt"}, collect(t, ZipArchiver{EntryOptions: EntryOptions{Names: NamesClean}}, path).names())
+	headers := collect(t, ZipArchiver{EntryOptions: EntryOptions{Names: NamesRelative}}, path).byPath()
+	assert.ElementsMatch(t, []string{"a/b.txt", "dir/c.txt", "abs/d.txt"}, slices.Collect(maps.Keys(headers)))
+	assert.Equal(t, `dir\c.txt`, headers["dir/c.txt"].RawName)
+	assert.Equal(t, "dir/c.txt", headers["dir/c.txt"].Path)
+}
+
+func TestTarArchiverNames(t *testing.T) {
+	path := writeTestFile(t, "names.tar", tarBytes(t, testEntry{name: "./usr/bin/tool", mode: 0755}))
+
+	headers := collect(t, TarArchiver{}, path).byPath()
+	require.Contains(t, headers, "usr/bin/tool")
+	assert.Equal(t, "./usr/bin/tool", headers["usr/bin/tool"].RawName)
+	assert.Equal(t, []string{"./usr/bin/tool"}, collect(t, TarArchiver{EntryOptions: EntryOptions{Names: NamesRaw}}, path).names())
+}
+
+func TestRpmArchiverNames(t *testing.T) {
+	for _, name := range collect(t, RpmArchiver{}, "./fixtures/test.rpm").names() {
+		assert.True(t, strings.HasPrefix(name, "./"), name)
+	}
+	for _, name := range collect(t, RpmArchiver{EntryOptions: EntryOptions{Names: NamesRelative}}, "./fixtures/test.rpm").names() {
+		assert.True(t, strings.HasPrefix(name, "usr/"), name)
+	}
+}
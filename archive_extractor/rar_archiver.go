@@ -2,38 +2,94 @@ package archive_extractor
 
 import (
 	"context"
+	"errors"
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/mholt/archives"
-	"os"
-	"strings"
+	"io"
 )
 
 type RarArchiver struct {
 	MaxCompressRatio   int64
 	MaxNumberOfEntries int
+	EntryOptions
+	// Password decrypts the encrypted entries and headers, PasswordProvider is called once they turn out to be encrypted
+	// when it is empty
+	Password         string
+	PasswordProvider PasswordProvider
+	// MaxSpoolSize bounds the temporary file holding the content of the regular files until the symlinks are all known,
+	// see TarArchiver
+	MaxSpoolSize int64
 }
 
 func (ra RarArchiver) ExtractArchive(path string,
 	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
-	ctx := context.Background()
-	maxBytesLimit, err := maxBytesLimit(path, ra.MaxCompressRatio)
+	return ra.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (ra RarArchiver) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, rarFile, err := openFileSource(path)
 	if err != nil {
-		return archiver_errors.New(err)
+		return archiver_errors.NewOpenError(path, err)
 	}
-	provider := LimitAggregatingReadCloserProvider{
-		Limit: maxBytesLimit,
+	defer func() {
+		_ = rarFile.Close()
+	}()
+	return ra.ExtractSource(ctx, source, processingFunc, params)
+}
+
+func (ra RarArchiver) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := ra.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+func (ra RarArchiver) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, ra.EntryOptions, FoldersSkip)
+	if err != nil {
+		return result, err
 	}
-	format := archives.Rar{}
-	rarFile, err := os.Open(path)
+	return result, result.finish(ra.extractEntries(ctx, source, processEntry))
+}
+
+func (ra RarArchiver) extractEntries(ctx context.Context, source *Source, processEntry processEntryFunc) error {
+	maxBytesLimit, err := source.maxBytesLimit(ra.MaxCompressRatio)
 	if err != nil {
-		return archiver_errors.NewOpenError(path, err)
+		return archiver_errors.New(err)
 	}
+	var replay *sourceReplay
 	defer func() {
-		_ = rarFile.Close()
+		if replay != nil {
+			replay.close()
+		}
 	}()
-	err = extract(ctx, format, rarFile, ra.MaxNumberOfEntries, provider, processingFunc, params)
-	if err != nil && strings.Contains(err.Error(), archiver_errors.RarDecodeError.Error()) {
-		return archiver_errors.NewOpenError(path, err)
+	password := newArchivePassword(source.Name, ra.Password, ra.PasswordProvider)
+	err = password.extract(processEntry, func(password string, processEntry processEntryFunc, stopAtEncrypted bool) error {
+		provider := &LimitAggregatingReadCloserProvider{
+			Limit: maxBytesLimit,
+		}
+		format := archives.Rar{Password: password}
+		links := newLinkExtraction(false, ra.MaxSpoolSize, ra.EntryOptions, provider, processEntry)
+		// the stream is read again by the pass with the password of the provider
+		var reader io.Reader
+		var err error
+		if replay == nil {
+			replay = newSourceReplay(source, links.maxSpoolSize)
+			reader = replay.stream
+		} else if reader, err = replay.replay(); err != nil {
+			return err
+		}
+		err = extract(ctx, format, reader, ra.MaxNumberOfEntries, provider, links.selects, links.processing(ctx, stopAtEncrypted))
+		return links.finish(ctx, err, func(selectEntry entrySelector, processEntry processEntryFunc) error {
+			reader, err := replay.replay()
+			if err != nil {
+				return err
+			}
+			return extract(ctx, format, reader, 0, provider, selectEntry, processEntry)
+		})
+	})
+	if err = classifyError(err); errors.Is(err, archiver_errors.ErrNotThisFormat) {
+		return archiver_errors.NewOpenError(source.Name, err)
 	}
 	return err
 }
@@ -0,0 +1,182 @@
+package archive_extractor
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+)
+
+const (
+	DefaultMaxDepth = 8
+	// NestedPathSeparator separates the path of a nested archive from the paths of its entries
+	NestedPathSeparator = "!/"
+)
+
+// RecursiveExtractor identifies the format of an archive, extracts it, and in turn extracts every entry
+// identified with at least ConfidenceMedium as an archive or a compressed file (see Identify).
+// Entries of nested archives are passed to processingFunc with ArchiveHeader.Path holding their virtual path,
+// the nested archives themselves are not.
+// The MaxCompressRatio budget is computed from the size of the outer archive and MaxNumberOfEntries counts
+// the entries of all archives, both are shared by all nesting levels so nesting can't be used to bypass them.
+// They are also applied to every nested archive on its own.
+// Zip and 7z archives nested in a compressed file are copied to a temporary file within the MaxCompressRatio budget,
+// without MaxCompressRatio they fail with ErrSpoolUnknownSize.
+// Errors of nested archives that can't be extracted are returned as a MultiError once all the other entries are processed.
+type RecursiveExtractor struct {
+	MaxCompressRatio   int64
+	MaxNumberOfEntries int
+	EntryOptions
+	// Password and PasswordProvider decrypt the encrypted archives of all nesting levels,
+	// PasswordProvider is given the name of the nested archive
+	Password         string
+	PasswordProvider PasswordProvider
+	// MaxDepth is the deepest nesting level that is extracted, the outer archive being at level 0, so 0 extracts
+	// the outer archive only. Deeper archives are passed to processingFunc as regular entries. A negative MaxDepth means DefaultMaxDepth.
+	MaxDepth int
+}
+
+func (re RecursiveExtractor) ExtractArchive(path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	return re.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (re RecursiveExtractor) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return re.ExtractSource(ctx, source, processingFunc, params)
+}
+
+func (re RecursiveExtractor) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := re.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+// ExtractWithResult reports the entries of all nesting levels, RpmPkg is not set
+func (re RecursiveExtractor) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	// the folders and the names are handled by the archivers of every nesting level
+	entryOptions := re.EntryOptions
+	entryOptions.Folders, entryOptions.Names = FoldersExplicit, NamesDefault
+	result, processEntry, err := newExtraction(processingFunc, entryOptions, FoldersExplicit)
+	if err != nil {
+		return result, err
+	}
+	maxBytesLimit, err := source.maxBytesLimit(re.MaxCompressRatio)
+	if err != nil {
+		return result, err
+	}
+	maxDepth := re.MaxDepth
+	if maxDepth < 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	options := []Option{WithMaxCompressRatio(re.MaxCompressRatio), WithMaxNumberOfEntries(re.MaxNumberOfEntries),
+		WithPassword(re.Password), WithPasswordProvider(re.PasswordProvider), WithFolders(re.Folders), WithNames(re.Names)}
+	r := &recursiveExtraction{
+		options:            options,
+		maxDepth:           maxDepth,
+		maxNumberOfEntries: re.MaxNumberOfEntries,
+		provider:           &LimitAggregatingReadCloserProvider{Limit: maxBytesLimit},
+		processEntry:       processEntry,
+	}
+	identification, err := IdentifySource(source, r.options...)
+	if err != nil {
+		return result, err
+	}
+	return result, result.finish(r.extract(ctx, identification.Archiver, source, filepath.Base(source.Name)+NestedPathSeparator, 0))
+}
+
+type recursiveExtraction struct {
+	options            []Option
+	maxDepth           int
+	maxNumberOfEntries int
+	entriesCount       int
+	// provider is shared by the entries of all nesting levels
+	provider     *LimitAggregatingReadCloserProvider
+	processEntry processEntryFunc
+}
+
+func (r *recursiveExtraction) extract(ctx context.Context, archiver Archiver, source *Source, pathPrefix string, depth int) error {
+	var multiErrors *archiver_errors.MultiError
+	processEntry := func(header *ArchiveHeader) error {
+		header.Path = pathPrefix + header.Name
+		header.ArchiveReader = r.provider.CreateLimitAggregatingReadCloser(header.ArchiveReader)
+		if depth < r.maxDepth && !header.IsFolder {
+			nested := NewReaderSource(header.Name, header.ArchiveReader, header.Size)
+			if _, ok := archiver.(Decompressor); ok {
+				// the Size of a decompressed file is the size of the compressed file,
+				// the MaxCompressRatio budget bounds the copy of a nested zip or 7z archive instead
+				nested.spoolLimit = -1
+				if r.provider.Limit > 0 {
+					nested.spoolLimit = r.provider.Limit
+				}
+			}
+			identification, err := IdentifySource(nested, r.options...)
+			if err == nil && identification.Confidence >= ConfidenceMedium {
+				err = r.extract(ctx, identification.Archiver, nested, header.Path+NestedPathSeparator, depth+1)
+				if err == nil || r.isFatal(ctx, err) {
+					return err
+				}
+				multiErrors = archiver_errors.Append(multiErrors, archiver_errors.NewArchiverExtractorError(header.Path, err))
+				return nil
+			}
+			if err != nil && r.isFatal(ctx, err) {
+				return err
+			}
+			// the bytes read while identifying the entry are buffered by the nested source
+			header.ArchiveReader = nested.stream()
+		}
+		if r.maxNumberOfEntries != 0 && r.entriesCount >= r.maxNumberOfEntries {
+			return ErrTooManyEntries
+		}
+		r.entriesCount++
+		if err := r.processEntry(header); err != nil {
+			if errors.Is(err, archiver_errors.ErrCallback) {
+				return err
+			}
+			// the errors of the entry limits and the stop of the extraction are not mistaken for errors of a nested archive either
+			return &archiver_errors.CallbackError{Err: err}
+		}
+		return nil
+	}
+	err := extractSource(ctx, archiver, source, processEntry, map[string]interface{}{})
+	//multi error can be skipped or not skipped by caller, therefore we distinguish between err and multiErrors
+	if err == nil && multiErrors != nil {
+		return multiErrors
+	}
+	return err
+}
+
+// isFatal tells whether err stops the whole extraction rather than only the extraction of a nested archive
+func (r *recursiveExtraction) isFatal(ctx context.Context, err error) bool {
+	return ctx.Err() != nil ||
+		errors.Is(err, archiver_errors.ErrCallback) ||
+		errors.Is(err, ErrTooManyEntries) ||
+		IsErrCompressLimitReached(err)
+}
+
+// extractSource extracts source with archiver, archivers that can only extract files get a temporary copy of the source.
+// params are only passed to archivers which don't implement ResultArchiver.
+func extractSource(ctx context.Context, archiver Archiver, source *Source, processEntry processEntryFunc, params map[string]any) error {
+	if resultArchiver, ok := archiver.(ResultArchiver); ok {
+		_, err := resultArchiver.ExtractWithResult(ctx, source, processEntry)
+		return err
+	}
+	processingFunc := func(header *ArchiveHeader, params map[string]interface{}) error {
+		return processEntry(header)
+	}
+	if sourceArchiver, ok := archiver.(SourceArchiver); ok {
+		return sourceArchiver.ExtractSource(ctx, source, processingFunc, params)
+	}
+	f, cleanup, err := source.spool()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return archiver.ExtractArchiveContext(ctx, f.Name(), processingFunc, params)
+}
@@ -0,0 +1,131 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nestedArchive(t *testing.T) string {
+	jar := zipBytes(t, testEntry{name: "x.class", content: []byte("class content")}, testEntry{name: "META-INF/MANIFEST.MF", content: []byte("Manifest-Version: 1.0\n")})
+	war := zipBytes(t, testEntry{name: "WEB-INF/lib/b.jar", content: jar}, testEntry{name: "index.html", content: []byte("<html></html>")})
+	return tarGzFile(t, "outer.tar.gz",
+		testEntry{name: "lib/a.war", content: war},
+		testEntry{name: "doc/readme.txt.gz", content: gzipBytes(t, []byte("read me"))},
+		testEntry{name: "plain.txt", content: []byte("plain")})
+}
+
+func TestRecursiveExtractor(t *testing.T) {
+	entries := collect(t, RecursiveExtractor{MaxCompressRatio: 100, MaxDepth: DefaultMaxDepth}, nestedArchive(t)).contents()
+	assert.Equal(t, map[string]string{
+		"outer.tar.gz!/lib/a.war!/WEB-INF/lib/b.jar!/x.class":              "class content",
+		"outer.tar.gz!/lib/a.war!/WEB-INF/lib/b.jar!/META-INF/MANIFEST.MF": "Manifest-Version: 1.0\n",
+		"outer.tar.gz!/lib/a.war!/index.html":                              "<html></html>",
+		"outer.tar.gz!/doc/readme.txt.gz!/readme.txt":                      "read me",
+		"outer.tar.gz!/plain.txt":                                          "plain",
+	}, entries)
+}
+
+func TestRecursiveExtractorMaxDepth(t *testing.T) {
+	entries := collect(t, RecursiveExtractor{MaxDepth: 1}, nestedArchive(t)).contents()
+	assert.Contains(t, entries, "outer.tar.gz!/lib/a.war!/WEB-INF/lib/b.jar")
+	assert.Contains(t, entries, "outer.tar.gz!/lib/a.war!/index.html")
+	assert.True(t, strings.HasPrefix(entries["outer.tar.gz!/lib/a.war!/WEB-INF/lib/b.jar"], "PK\x03\x04"))
+
+	// only the outer archive is extracted
+	entries = collect(t, RecursiveExtractor{}, nestedArchive(t)).contents()
+	assert.ElementsMatch(t, []string{"outer.tar.gz!/lib/a.war", "outer.tar.gz!/doc/readme.txt.gz", "outer.tar.gz!/plain.txt"}, slices.Collect(maps.Keys(entries)))
+	assert.True(t, strings.HasPrefix(entries["outer.tar.gz!/lib/a.war"], "PK\x03\x04"))
+
+	entries = collect(t, RecursiveExtractor{MaxDepth: -1}, nestedArchive(t)).contents()
+	assert.Contains(t, entries, "outer.tar.gz!/lib/a.war!/WEB-INF/lib/b.jar!/x.class")
+}
+
+func TestRecursiveExtractorSharedRatioBudget(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), 1024*1024)
+	inner := gzipBytes(t, large)
+	// identical copies are deduplicated by the outer compression, so the ratios multiply
+	var copies []testEntry
+	for i := 0; i < 10; i++ {
+		copies = append(copies, testEntry{name: fmt.Sprintf("copy%d.txt.gz", i), content: inner})
+	}
+	path := tarGzFile(t, "outer.tar.gz", copies...)
+	// each nesting level on its own is within this ratio, the whole content is not
+	ratio := 2 * int64(len(large)) / int64(len(inner))
+	err := Decompressor{MaxCompressRatio: ratio}.ExtractSource(context.Background(),
+		NewReaderSource("copy.txt.gz", bytes.NewReader(inner), int64(len(inner))), processingReadingFunc, params())
+	require.NoError(t, err)
+	err = TarArchiver{MaxCompressRatio: ratio}.ExtractArchive(path, processingReadingFunc, params())
+	require.NoError(t, err)
+
+	err = RecursiveExtractor{MaxCompressRatio: ratio, MaxDepth: DefaultMaxDepth}.ExtractArchive(path, processingReadingFunc, params())
+	assert.True(t, IsErrCompressLimitReached(err))
+	err = RecursiveExtractor{MaxCompressRatio: ratio * 100, MaxDepth: DefaultMaxDepth}.ExtractArchive(path, processingReadingFunc, params())
+	assert.NoError(t, err)
+}
+
+func TestRecursiveExtractorCompressedZip(t *testing.T) {
+	path := writeTestFile(t, "a.zip.gz", gzipBytes(t, zipBytes(t, fileEntry("a.txt", "content"))))
+	entries := collect(t, RecursiveExtractor{MaxCompressRatio: 100, MaxDepth: DefaultMaxDepth}, path).contents()
+	assert.Equal(t, map[string]string{"a.zip.gz!/a.zip!/a.txt": "content"}, entries)
+
+	// the zip archive is copied to a temporary file only within the MaxCompressRatio budget
+	_, err := collectWithError(t, RecursiveExtractor{MaxDepth: DefaultMaxDepth}, path)
+	assert.ErrorIs(t, err, ErrSpoolUnknownSize)
+}
+
+func TestRecursiveExtractorSharedMaxNumberOfEntries(t *testing.T) {
+	re := RecursiveExtractor{MaxNumberOfEntries: 4, MaxDepth: DefaultMaxDepth}
+	err := re.ExtractArchive(nestedArchive(t), processingReadingFunc, params())
+	assert.ErrorIs(t, err, ErrTooManyEntries)
+	re = RecursiveExtractor{MaxNumberOfEntries: 5, MaxDepth: DefaultMaxDepth}
+	err = re.ExtractArchive(nestedArchive(t), processingReadingFunc, params())
+	assert.NoError(t, err)
+}
+
+func TestRecursiveExtractorCorruptedNestedArchive(t *testing.T) {
+	path := tarGzFile(t, "outer.tar.gz",
+		testEntry{name: "broken.zip", content: []byte("PK\x03\x04 not really a zip")},
+		testEntry{name: "plain.txt", content: []byte("plain")})
+	extracted, err := collectWithError(t, RecursiveExtractor{MaxDepth: DefaultMaxDepth}, path)
+	var multiErr *archiver_errors.MultiError
+	require.True(t, errors.As(err, &multiErr))
+	assert.Len(t, multiErr.Errors, 1)
+	assert.Contains(t, multiErr.Error(), "outer.tar.gz!/broken.zip")
+	assert.Equal(t, map[string]string{"outer.tar.gz!/plain.txt": "plain"}, extracted.contents())
+}
+
+func TestRecursiveExtractorProcessingError(t *testing.T) {
+	expectedErr := errors.New("processing error")
+	err := RecursiveExtractor{MaxDepth: DefaultMaxDepth}.ExtractArchive(nestedArchive(t), func(header *ArchiveHeader, params map[string]interface{}) error {
+		return expectedErr
+	}, params())
+	assert.ErrorIs(t, err, expectedErr)
+	// the error of a nested entry is wrapped once, like the ones of the outer entries
+	err = RecursiveExtractor{MaxDepth: DefaultMaxDepth}.ExtractArchive(nestedArchive(t), func(header *ArchiveHeader, params map[string]interface{}) error {
+		if header.Path == "outer.tar.gz!/lib/a.war!/index.html" {
+			return expectedErr
+		}
+		return nil
+	}, params())
+	require.ErrorIs(t, err, archiver_errors.ErrCallback)
+	require.ErrorIs(t, err, expectedErr)
+	var wrappers []error
+	for wrapped := err; wrapped != expectedErr; wrapped = errors.Unwrap(wrapped) {
+		if wrapped.Error() == expectedErr.Error() {
+			wrappers = append(wrappers, wrapped)
+		}
+	}
+	assert.Equal(t, []error{&archiver_errors.CallbackError{Err: expectedErr}}, wrappers)
+}
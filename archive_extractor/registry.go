@@ -0,0 +1,162 @@
+package archive_extractor
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// MagicMatcher matches Magic at Offset from the beginning of the archive content.
+// Only the first 512 bytes of the content are available to matchers.
+type MagicMatcher struct {
+	Offset int
+	Magic  []byte
+}
+
+func (mm MagicMatcher) match(head []byte) bool {
+	return len(head) >= mm.Offset+len(mm.Magic) && bytes.Equal(head[mm.Offset:mm.Offset+len(mm.Magic)], mm.Magic)
+}
+
+// FormatRegistration teaches format identification, and everything built on it, about an archive format
+type FormatRegistration struct {
+	Format Format
+	// NewArchiver creates the archiver of the format with the limits requested by the caller
+	NewArchiver func(config ArchiverConfig) Archiver
+	// Matchers identify the format by its content, any of them matching is enough
+	Matchers []MagicMatcher
+	// Extensions are used when the content doesn't match, they are compared case insensitively and include the dot
+	Extensions []string
+	// AcceptsCompressed means the archiver decompresses the archive by itself (like tar),
+	// so the format is also matched against the decompressed content of compressed files
+	AcceptsCompressed bool
+}
+
+var (
+	registryMutex sync.RWMutex
+	registrations []FormatRegistration
+)
+
+func init() {
+	newFormat := func(format Format, newArchiver func(config ArchiverConfig) Archiver, matchers []MagicMatcher, extensions ...string) FormatRegistration {
+		return FormatRegistration{Format: format, NewArchiver: newArchiver, Matchers: matchers, Extensions: extensions}
+	}
+	tar := newFormat(FormatTar, func(c ArchiverConfig) Archiver {
+		return TarArchiver{MaxCompressRatio: c.MaxCompressRatio, MaxNumberOfEntries: c.MaxNumberOfEntries, EntryOptions: c.EntryOptions,
+			HardlinkAliases: c.HardlinkAliases, MaxSpoolSize: c.MaxSpoolSize}
+	}, []MagicMatcher{{Offset: tarMagicOffset, Magic: tarMagic}}, ".tar", ".tgz", ".tbz2", ".txz", ".tlzma")
+	tar.AcceptsCompressed = true
+	builtins := []FormatRegistration{
+		newFormat(FormatZip, func(c ArchiverConfig) Archiver {
+			return ZipArchiver{MaxCompressRatio: c.MaxCompressRatio, MaxNumberOfEntries: c.MaxNumberOfEntries, EntryOptions: c.EntryOptions,
+				Password: c.Password, PasswordProvider: c.PasswordProvider, Workers: c.Workers}
+		}, []MagicMatcher{{Magic: zipMagic}, {Magic: zipEmptyMagic}}, zipExtensions...),
+		tar,
+		newFormat(FormatDeb, func(c ArchiverConfig) Archiver {
+			return DebArchiver{MaxCompressRatio: c.MaxCompressRatio, MaxNumberOfEntries: c.MaxNumberOfEntries, EntryOptions: c.EntryOptions}
+		}, []MagicMatcher{{Magic: debMagic}}, ".deb", ".udeb"),
+		newFormat(FormatRpm, func(c ArchiverConfig) Archiver {
+			return RpmArchiver{MaxCompressRatio: c.MaxCompressRatio, MaxNumberOfEntries: c.MaxNumberOfEntries, EntryOptions: c.EntryOptions}
+		}, []MagicMatcher{{Magic: rpmMagic}}, ".rpm"),
+		newFormat(FormatSevenZip, func(c ArchiverConfig) Archiver {
+			return SevenZipArchiver{MaxCompressRatio: c.MaxCompressRatio, MaxNumberOfEntries: c.MaxNumberOfEntries, EntryOptions: c.EntryOptions,
+				Password: c.Password, PasswordProvider: c.PasswordProvider, MaxSpoolSize: c.MaxSpoolSize}
+		}, []MagicMatcher{{Magic: sevenZipMagic}}, ".7z"),
+		newFormat(FormatRar, func(c ArchiverConfig) Archiver {
+			return RarArchiver{MaxCompressRatio: c.MaxCompressRatio, MaxNumberOfEntries: c.MaxNumberOfEntries, EntryOptions: c.EntryOptions,
+				Password: c.Password, PasswordProvider: c.PasswordProvider, MaxSpoolSize: c.MaxSpoolSize}
+		}, []MagicMatcher{{Magic: rarMagic}}, ".rar"),
+		newFormat(FormatCompressed, func(c ArchiverConfig) Archiver {
+			return Decompressor{MaxCompressRatio: c.MaxCompressRatio, EntryOptions: c.EntryOptions}
+		}, nil),
+	}
+	for _, registration := range builtins {
+		if err := RegisterFormat(registration); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterFormat adds a format to the registry, replacing a previous registration of the same format.
+// Formats registered later are matched first, so a registration can refine the built-in formats
+// (for example a firmware bundle which is a zip file with an additional signature).
+func RegisterFormat(registration FormatRegistration) error {
+	if registration.Format == FormatUnknown {
+		return errors.New("format registration must have a format name")
+	}
+	if registration.NewArchiver == nil {
+		return errors.New("format registration must have an archiver factory")
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	for i, existing := range registrations {
+		if existing.Format == registration.Format {
+			registrations = append(registrations[:i], registrations[i+1:]...)
+			break
+		}
+	}
+	registrations = append(registrations, registration)
+	return nil
+}
+
+// UnregisterFormat removes a format from the registry
+func UnregisterFormat(format Format) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	for i, existing := range registrations {
+		if existing.Format == format {
+			registrations = append(registrations[:i], registrations[i+1:]...)
+			return
+		}
+	}
+}
+
+// lookupFormat returns the registration of format
+func lookupFormat(format Format) (FormatRegistration, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	for _, registration := range registrations {
+		if registration.Format == format {
+			return registration, true
+		}
+	}
+	return FormatRegistration{}, false
+}
+
+// matchFormat returns the last registered format that matches head, considering only
+// formats that accept compressed content when compressed is true
+func matchFormat(head []byte, compressed bool) Format {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	for i := len(registrations) - 1; i >= 0; i-- {
+		registration := registrations[i]
+		if compressed && !registration.AcceptsCompressed {
+			continue
+		}
+		for _, matcher := range registration.Matchers {
+			if matcher.match(head) {
+				return registration.Format
+			}
+		}
+	}
+	return FormatUnknown
+}
+
+// matchExtension returns the last registered format having the extension of name
+func matchExtension(name string, compressed bool) Format {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	name = strings.ToLower(name)
+	for i := len(registrations) - 1; i >= 0; i-- {
+		registration := registrations[i]
+		if compressed && !registration.AcceptsCompressed {
+			continue
+		}
+		for _, ext := range registration.Extensions {
+			if strings.HasSuffix(name, strings.ToLower(ext)) {
+				return registration.Format
+			}
+		}
+	}
+	return FormatUnknown
+}
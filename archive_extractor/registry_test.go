@@ -0,0 +1,100 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const firmwareFormat Format = "firmware"
+
+type firmwareArchiver struct {
+	config ArchiverConfig
+}
+
+func (fa firmwareArchiver) ExtractArchive(path string, processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	return fa.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (fa firmwareArchiver) ExtractArchiveContext(ctx context.Context, path string, processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	return processingFunc(NewArchiveHeader(nil, "firmware.bin", 0, 0), params)
+}
+
+func registerFirmwareFormat(t *testing.T) {
+	err := RegisterFormat(FormatRegistration{
+		Format: firmwareFormat,
+		NewArchiver: func(config ArchiverConfig) Archiver {
+			return firmwareArchiver{config: config}
+		},
+		Matchers:   []MagicMatcher{{Offset: 4, Magic: []byte("FWB1")}},
+		Extensions: []string{".fwb"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		UnregisterFormat(firmwareFormat)
+	})
+}
+
+func TestRegisterFormat_IdentifiedByMagic(t *testing.T) {
+	registerFirmwareFormat(t)
+	path := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, os.WriteFile(path, []byte("\x00\x00\x00\x00FWB1content"), 0644))
+	identification, err := Identify(path, WithMaxNumberOfEntries(5))
+	require.NoError(t, err)
+	assert.Equal(t, firmwareFormat, identification.Format)
+	assert.Equal(t, ConfidenceHigh, identification.Confidence)
+	require.IsType(t, firmwareArchiver{}, identification.Archiver)
+	assert.Equal(t, 5, identification.Archiver.(firmwareArchiver).config.MaxNumberOfEntries)
+
+	funcParams := params()
+	require.NoError(t, Extract(path, processingFunc, funcParams))
+	assert.Equal(t, "firmware.bin", funcParams["archiveData"].(*ArchiveData).Name)
+}
+
+func TestRegisterFormat_IdentifiedByExtension(t *testing.T) {
+	registerFirmwareFormat(t)
+	path := filepath.Join(t.TempDir(), "bundle.FWB")
+	require.NoError(t, os.WriteFile(path, []byte("unknown content"), 0644))
+	identification, err := Identify(path)
+	require.NoError(t, err)
+	assert.Equal(t, firmwareFormat, identification.Format)
+	assert.Equal(t, ConfidenceLow, identification.Confidence)
+}
+
+func TestRegisterFormat_NotMatchedInsideCompression(t *testing.T) {
+	registerFirmwareFormat(t)
+	identification, err := Identify("./fixtures/test.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, FormatTar, identification.Format)
+}
+
+func TestRegisterFormat_OverridesBuiltinFormat(t *testing.T) {
+	err := RegisterFormat(FormatRegistration{
+		Format: "signed-zip",
+		NewArchiver: func(config ArchiverConfig) Archiver {
+			return firmwareArchiver{config: config}
+		},
+		Matchers: []MagicMatcher{{Magic: zipMagic}},
+	})
+	require.NoError(t, err)
+	defer UnregisterFormat("signed-zip")
+	identification, err := Identify("./fixtures/test.zip")
+	require.NoError(t, err)
+	assert.Equal(t, Format("signed-zip"), identification.Format)
+
+	UnregisterFormat("signed-zip")
+	identification, err = Identify("./fixtures/test.zip")
+	require.NoError(t, err)
+	assert.Equal(t, FormatZip, identification.Format)
+}
+
+func TestRegisterFormat_Invalid(t *testing.T) {
+	assert.Error(t, RegisterFormat(FormatRegistration{Format: firmwareFormat}))
+	assert.Error(t, RegisterFormat(FormatRegistration{NewArchiver: func(ArchiverConfig) Archiver { return nil }}))
+}
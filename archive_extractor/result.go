@@ -0,0 +1,168 @@
+package archive_extractor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ExtractResult describes a finished extraction
+type ExtractResult struct {
+	// EntriesCount is the number of entries passed to the processing function
+	EntriesCount int
+	// BytesRead is the number of uncompressed bytes read from the entries
+	BytesRead int64
+	// RpmPkg is the package metadata, set by RpmArchiver once an entry was processed
+	RpmPkg *RpmPkg
+	// mutex guards EntriesCount and stop when the entries are processed concurrently
+	mutex sync.Mutex
+	stop  *stopError
+}
+
+// ResultArchiver is implemented by the archivers that report an ExtractResult.
+// The processing function gets no params, the state it needs is usually captured by a closure (see ExtractTyped).
+type ResultArchiver interface {
+	ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error)
+}
+
+// ExtractTyped extracts the archive stored in path with archiver, passing state to every call of processingFunc.
+// Archivers that don't implement ResultArchiver get a result holding only the entries count and the bytes read.
+func ExtractTyped[T any](ctx context.Context, archiver Archiver, path string, processingFunc func(*ArchiveHeader, T) error, state T) (*ExtractResult, error) {
+	resultArchiver, ok := archiver.(ResultArchiver)
+	if !ok {
+		return extractCounting(ctx, archiver, path, func(header *ArchiveHeader) error {
+			return processingFunc(header, state)
+		})
+	}
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return resultArchiver.ExtractWithResult(ctx, source, func(header *ArchiveHeader) error {
+		return processingFunc(header, state)
+	})
+}
+
+// ExtractSourceTyped works like ExtractTyped on a source, archiver must implement ResultArchiver or SourceArchiver
+func ExtractSourceTyped[T any](ctx context.Context, archiver Archiver, source *Source, processingFunc func(*ArchiveHeader, T) error, state T) (*ExtractResult, error) {
+	processEntry := func(header *ArchiveHeader) error {
+		return processingFunc(header, state)
+	}
+	if resultArchiver, ok := archiver.(ResultArchiver); ok {
+		return resultArchiver.ExtractWithResult(ctx, source, processEntry)
+	}
+	result := &ExtractResult{}
+	err := extractSource(ctx, archiver, source, result.counting(withCallbackErrors(processEntry)), map[string]interface{}{})
+	return result, result.finish(err)
+}
+
+func extractCounting(ctx context.Context, archiver Archiver, path string, processEntry processEntryFunc) (*ExtractResult, error) {
+	result := &ExtractResult{}
+	err := archiver.ExtractArchiveContext(ctx, path, func(header *ArchiveHeader, params map[string]interface{}) error {
+		return result.counting(withCallbackErrors(processEntry))(header)
+	}, map[string]interface{}{})
+	return result, result.finish(err)
+}
+
+// newExtraction creates the result of an extraction and wraps processingFunc with the processing
+// shared by all the archivers: classifying its errors, normalising the names, reporting the folders, skipping the entries the filter doesn't select, counting the entries into the result,
+// enforcing the limits of every entry and computing their digests. defaultFolders is the folder reporting the archiver always had, see FolderReporting.
+func newExtraction(processingFunc func(*ArchiveHeader) error, options EntryOptions, defaultFolders FolderReporting) (*ExtractResult, processEntryFunc, error) {
+	result := &ExtractResult{}
+	if err := options.Filter.validate(); err != nil {
+		return result, nil, err
+	}
+	processEntry, err := withDigests(withCallbackErrors(processingFunc), options.Digests)
+	if err != nil {
+		return result, nil, err
+	}
+	limits := entryLimits{options.MaxEntrySize, options.MaxEntryCompressRatio}
+	folders := options.Folders.or(defaultFolders)
+	return result, options.Names.apply(folders.apply(options.Filter.apply(options.Names, result.counting(limits.apply(processEntry))))), nil
+}
+
+// processEntryFunc is the processing function of the typed API
+type processEntryFunc func(*ArchiveHeader) error
+
+// withParams adapts a processing function of the map based API to the typed API
+func withParams(processingFunc processingArchiveFunc, params map[string]any) processEntryFunc {
+	return func(header *ArchiveHeader) error {
+		return processingFunc(header, params)
+	}
+}
+
+// withCallbackErrors wraps the errors of processEntry in a CallbackError, see classifyCallbackError
+func withCallbackErrors(processEntry processEntryFunc) processEntryFunc {
+	return func(header *ArchiveHeader) error {
+		return classifyCallbackError(processEntry(header))
+	}
+}
+
+// counting counts the entries passed to processEntry and the bytes read from them into the result, except for the
+// entries processEntry returns SkipEntry for. StopExtraction is turned into the stop error of the result (see finish).
+func (r *ExtractResult) counting(processEntry processEntryFunc) processEntryFunc {
+	return func(header *ArchiveHeader) error {
+		reader := &countingReader{reader: header.ArchiveReader}
+		header.ArchiveReader = reader
+		err := processEntry(header)
+		if errors.Is(err, SkipEntry) {
+			return nil
+		}
+		r.mutex.Lock()
+		r.EntriesCount++
+		r.mutex.Unlock()
+		atomic.AddInt64(&r.BytesRead, reader.count)
+		if errors.Is(err, StopExtraction) {
+			return r.stopError()
+		}
+		return err
+	}
+}
+
+// stopError is returned to the archiver when processingFunc returns StopExtraction. Every extraction has its own, and
+// it doesn't wrap StopExtraction, so that the extraction of a nested archive (see RecursiveExtractor) isn't stopped cleanly
+// when the extraction of the archive containing it is.
+type stopError struct {
+	err error
+}
+
+func (se *stopError) Error() string {
+	return se.err.Error()
+}
+
+func (r *ExtractResult) stopError() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stop == nil {
+		r.stop = &stopError{err: StopExtraction}
+	}
+	return r.stop
+}
+
+// finish returns the error of the extraction with its kind (see classifyError), nil when processingFunc stopped it with StopExtraction
+func (r *ExtractResult) finish(err error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stop != nil && errors.Is(err, r.stop) {
+		return nil
+	}
+	return classifyError(err)
+}
+
+// countingReader counts the bytes read from an entry, and adds their kind to the errors reading it (see classifyError)
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.count += int64(n)
+	if err != nil && err != io.EOF {
+		err = classifyError(err)
+	}
+	return n, err
+}
@@ -0,0 +1,158 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedState struct {
+	names []string
+	size  int64
+}
+
+func collectTyped(header *ArchiveHeader, state *typedState) error {
+	state.names = append(state.names, header.Name)
+	state.size += header.Size
+	_, err := io.Copy(io.Discard, header.ArchiveReader)
+	return err
+}
+
+func TestExtractTyped(t *testing.T) {
+	state := &typedState{}
+	result, err := ExtractTyped(context.Background(), ZipArchiver{}, "./fixtures/testwithmanyfiles.zip", collectTyped, state)
+	require.NoError(t, err)
+	assert.Equal(t, 100, result.EntriesCount)
+	assert.Len(t, state.names, 100)
+	assert.Equal(t, state.size, result.BytesRead)
+	assert.Nil(t, result.RpmPkg)
+}
+
+func TestExtractTypedRpmPkg(t *testing.T) {
+	state := &typedState{}
+	result, err := ExtractTyped(context.Background(), RpmArchiver{}, "./fixtures/test.rpm", collectTyped, state)
+	require.NoError(t, err)
+	assert.Equal(t, 15, result.EntriesCount)
+	require.NotNil(t, result.RpmPkg)
+	assert.Equal(t, "php-zstd-devel", result.RpmPkg.Name)
+	assert.Equal(t, "0.4.11", result.RpmPkg.Version)
+}
+
+func TestRpmArchiverExtractSourceKeepsRpmPkgParam(t *testing.T) {
+	rpmPkg := &RpmPkg{Name: "preset"}
+	funcParams := params()
+	funcParams["rpmPkg"] = rpmPkg
+	err := RpmArchiver{}.ExtractArchive("./fixtures/test.rpm", processingFunc, funcParams)
+	require.NoError(t, err)
+	assert.Same(t, rpmPkg, funcParams["rpmPkg"])
+}
+
+func TestRpmArchiverSetsRpmPkgParamDuringExtraction(t *testing.T) {
+	var seen []bool
+	err := RpmArchiver{}.ExtractArchive("./fixtures/test.rpm", func(header *ArchiveHeader, params map[string]interface{}) error {
+		_, ok := params["rpmPkg"]
+		seen = append(seen, ok)
+		return nil
+	}, map[string]interface{}{})
+	require.NoError(t, err)
+	// the package metadata is set once the first entry, a folder which isn't passed on, was processed
+	require.Greater(t, len(seen), 1)
+	assert.NotContains(t, seen[1:], false)
+}
+
+// mapOnlyArchiver only implements the map based API
+type mapOnlyArchiver struct {
+	archiver ZipArchiver
+}
+
+func (moa mapOnlyArchiver) ExtractArchive(path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	return moa.archiver.ExtractArchive(path, processingFunc, params)
+}
+
+func (moa mapOnlyArchiver) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	return moa.archiver.ExtractArchiveContext(ctx, path, processingFunc, params)
+}
+
+func TestExtractTypedMapOnlyArchiver(t *testing.T) {
+	state := &typedState{}
+	var archiver Archiver = mapOnlyArchiver{}
+	_, isResultArchiver := archiver.(ResultArchiver)
+	require.False(t, isResultArchiver)
+	result, err := ExtractTyped(context.Background(), archiver, "./fixtures/testwithmanyfiles.zip", collectTyped, state)
+	require.NoError(t, err)
+	assert.Equal(t, 100, result.EntriesCount)
+	assert.Equal(t, state.size, result.BytesRead)
+}
+
+func TestExtractSourceTyped(t *testing.T) {
+	f, err := os.Open("./fixtures/test.deb")
+	require.NoError(t, err)
+	defer f.Close()
+	state := &typedState{}
+	result, err := ExtractSourceTyped(context.Background(), DebArchiver{}, NewReaderSource("test.deb", f, -1), collectTyped, state)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.EntriesCount)
+	assert.Equal(t, state.size, result.BytesRead)
+}
+
+func TestStopExtraction(t *testing.T) {
+	tests := []struct {
+		archiver Archiver
+		path     string
+	}{
+		{ZipArchiver{}, "./fixtures/testwithmanyfiles.zip"},
+		{TarArchiver{}, "./fixtures/testmanylarge.tar.gz"},
+		{DebArchiver{}, "./fixtures/test.deb"},
+		{RpmArchiver{}, "./fixtures/test.rpm"},
+		{SevenZipArchiver{}, "./fixtures/testwithmultipleentries.7z"},
+		{RarArchiver{}, "./fixtures/testwithmanyfiles.rar"},
+		{Decompressor{}, "./fixtures/test.txt.gz"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			calls := 0
+			result, err := ExtractTyped(context.Background(), test.archiver, test.path, func(header *ArchiveHeader, calls *int) error {
+				*calls++
+				return StopExtraction
+			}, &calls)
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.EntriesCount)
+			assert.Equal(t, 1, calls)
+		})
+	}
+}
+
+func TestSkipEntry(t *testing.T) {
+	var names []string
+	za := ZipArchiver{EntryOptions: EntryOptions{Digests: []DigestAlgorithm{DigestSHA256}}}
+	result, err := ExtractTyped(context.Background(), za, "./fixtures/testwithmanyfiles.zip", func(header *ArchiveHeader, names *[]string) error {
+		*names = append(*names, header.Name)
+		if len(*names)%2 == 0 {
+			return SkipEntry
+		}
+		return nil
+	}, &names)
+	require.NoError(t, err)
+	assert.Len(t, names, 100)
+	assert.Equal(t, 50, result.EntriesCount)
+}
+
+func TestRecursiveExtractorStopExtraction(t *testing.T) {
+	var names []string
+	result, err := ExtractTyped(context.Background(), RecursiveExtractor{MaxDepth: DefaultMaxDepth}, nestedArchive(t), func(header *ArchiveHeader, names *[]string) error {
+		*names = append(*names, header.Path)
+		// the extraction of the archive containing the nested one stops too
+		return StopExtraction
+	}, &names)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer.tar.gz!/lib/a.war!/WEB-INF/lib/b.jar!/x.class"}, names)
+	assert.Equal(t, 1, result.EntriesCount)
+}
@@ -1,42 +1,87 @@
 package archive_extractor
 
 import (
+	"context"
 	"io"
-	"math"
+	"strings"
 
 	"github.com/cavaliercoder/go-cpio"
 	"github.com/jfrog/go-rpm/v2"
 
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/jfrog/go-archive-extractor/compression"
+	"github.com/jfrog/go-archive-extractor/utils"
 )
 
 type RpmArchiver struct {
 	MaxCompressRatio   int64
 	MaxNumberOfEntries int
+	EntryOptions
 }
 
 func (ra RpmArchiver) ExtractArchive(path string,
 	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
-	maxBytesLimit, err := maxBytesLimit(path, ra.MaxCompressRatio)
-	rpmFile, err := rpm.OpenPackageFile(path)
-	if compression.IsGetReaderError(err) {
-		return archiver_errors.New(err)
+	return ra.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (ra RpmArchiver) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ra.ExtractSource(ctx, source, processingFunc, params)
+}
+
+// ExtractSource stores the package metadata in params["rpmPkg"] once the first entry was processed, unless the key is already set
+func (ra RpmArchiver) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := ra.extract(ctx, source, withParams(processingFunc, params), func(rpmPkg *RpmPkg) {
+		if _, ok := params["rpmPkg"]; !ok {
+			params["rpmPkg"] = rpmPkg
+		}
+	})
+	return err
+}
+
+func (ra RpmArchiver) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	return ra.extract(ctx, source, processingFunc, func(*RpmPkg) {})
+}
+
+// extract passes the package metadata to pkgRead as soon as it is set in the result
+func (ra RpmArchiver) extract(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error, pkgRead func(*RpmPkg)) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, ra.EntryOptions, FoldersSkip)
+	if err != nil {
+		return result, err
 	}
+	return result, result.finish(ra.extractEntries(ctx, source, result, processEntry, pkgRead))
+}
+
+func (ra RpmArchiver) extractEntries(ctx context.Context, source *Source, result *ExtractResult, processEntry processEntryFunc, pkgRead func(*RpmPkg)) error {
+	maxBytesLimit, err := source.maxBytesLimit(ra.MaxCompressRatio)
+	if err != nil {
+		return err
+	}
+	stream := source.stream()
+	// reading the package headers leaves the stream at the beginning of the compressed payload
+	rpmFile, err := rpm.ReadPackageFile(stream)
 	if err != nil {
 		return err
 	}
 
-	headerEnd := ra.getHeadersEnd(rpmFile.Headers)
-	cReader, _, err := compression.NewReader(path, compression.WithSkipBytes(headerEnd))
+	cReader, _, err := compression.NewReaderFrom(stream, source.Name, compression.WithContext(ctx))
 	if err != nil {
-		return archiver_errors.New(err)
+		return archiver_errors.New(classifyError(err))
 	}
 	defer cReader.Close()
 
-	err = ra.readRpm(processingFunc, params, rpmFile, cReader, maxBytesLimit)
+	err = ra.readRpm(ctx, processEntry, result, pkgRead, rpmFile, cReader, maxBytesLimit)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
 	if err != nil && !IsErrCompressLimitReached(err) {
-		return archiver_errors.New(err)
+		return archiver_errors.New(classifyError(err))
 	}
 	if IsErrCompressLimitReached(err) {
 		return err
@@ -44,35 +89,24 @@ func (ra RpmArchiver) ExtractArchive(path string,
 	return nil
 }
 
-func (ra RpmArchiver) getHeadersEnd(headers []rpm.Header) int64 {
-	var end uint64
-	offset := 96
-	for i := 0; i < 2; i++ {
-		h := headers[i]
-		// set start and end offsets
-		start := offset
-		end = uint64(start + 16 + (16 * h.IndexCount) + h.Length)
-		offset = int(end)
-		// calculate location of the end of the header by padding to a multiple of 8
-		pad := 8 - int(math.Mod(float64(h.Length), 8))
-		if pad < 8 {
-			offset += pad
-		}
-	}
-	return int64(end)
-}
-
-func (ra RpmArchiver) readRpm(processingFunc func(*ArchiveHeader, map[string]interface{}) error,
-	params map[string]interface{}, rpmFile *rpm.PackageFile, fileReader io.Reader, maxBytesLimit int64) error {
+func (ra RpmArchiver) readRpm(ctx context.Context, processEntry processEntryFunc,
+	result *ExtractResult, pkgRead func(*RpmPkg), rpmFile *rpm.PackageFile, fileReader io.Reader, maxBytesLimit int64) error {
 	provider := LimitAggregatingReadCloserProvider{
 		Limit: maxBytesLimit,
 	}
 
+	files := make(map[string]rpm.FileInfo)
+	for _, fileInfo := range rpmFile.Files() {
+		files[fileInfo.Name()] = fileInfo
+	}
 	cpioReader := cpio.NewReader(fileReader)
-	rc := provider.CreateLimitAggregatingReadCloser(cpioReader)
+	rc := provider.CreateLimitAggregatingReadCloser(utils.NewContextReader(ctx, cpioReader))
 	defer rc.Close()
 	var count = 0
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if ra.MaxNumberOfEntries != 0 && count > ra.MaxNumberOfEntries {
 			return ErrTooManyEntries
 		}
@@ -84,13 +118,24 @@ func (ra RpmArchiver) readRpm(processingFunc func(*ArchiveHeader, map[string]int
 			break
 		}
 		count++
-		if archiveEntry != nil && !archiveEntry.Mode.IsDir() {
+		if archiveEntry != nil {
 			archiveHeader := NewArchiveHeader(rc, archiveEntry.Name, archiveEntry.ModTime.Unix(), archiveEntry.Size)
-			err = processingFunc(archiveHeader, params)
-			if _, ok := params["rpmPkg"]; !ok {
+			archiveHeader.setMode(archiveEntry.FileInfo().Mode())
+			archiveHeader.LinkTarget = archiveEntry.Linkname
+			archiveHeader.Uid = archiveEntry.UID
+			archiveHeader.Gid = archiveEntry.GID
+			archiveHeader.ModTimeNs = unixNano(archiveEntry.ModTime)
+			// the cpio entries have no owner names, they are in the package headers
+			if fileInfo, ok := files[strings.TrimPrefix(archiveEntry.Name, ".")]; ok {
+				archiveHeader.Uname = fileInfo.Owner()
+				archiveHeader.Gname = fileInfo.Group()
+			}
+			err = processEntry(archiveHeader)
+			if result.RpmPkg == nil {
 				modularityLabel := getModularityLabel(rpmFile)
-				params["rpmPkg"] = &RpmPkg{Name: rpmFile.Name(), Version: rpmFile.Version(), Release: rpmFile.Release(),
+				result.RpmPkg = &RpmPkg{Name: rpmFile.Name(), Version: rpmFile.Version(), Release: rpmFile.Release(),
 					Epoch: rpmFile.Epoch(), Licenses: []string{rpmFile.License()}, Vendor: rpmFile.Vendor(), ModularityLabel: modularityLabel}
+				pkgRead(result.RpmPkg)
 			}
 			if err != nil {
 				return err
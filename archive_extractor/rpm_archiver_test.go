@@ -3,9 +3,13 @@
 package archive_extractor
 
 import (
+	"context"
 	"fmt"
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
 	"testing"
 )
 
@@ -51,3 +55,31 @@ func TestRpmArchiverRatioOk(t *testing.T) {
 	err := za.ExtractArchive("./fixtures/test.rpm", processingFunc, params())
 	assert.NoError(t, err)
 }
+
+func TestRpmArchiverContextCanceledMidEntry(t *testing.T) {
+	za := &RpmArchiver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	processor := func(header *ArchiveHeader, params map[string]interface{}) error {
+		cancel()
+		return processingReadingFunc(header, params)
+	}
+	err := za.ExtractArchiveContext(ctx, "./fixtures/test.rpm", processor, params())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRpmArchiverExtractSourceStream(t *testing.T) {
+	f, err := os.Open("./fixtures/test.rpm")
+	require.NoError(t, err)
+	defer f.Close()
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	za := &RpmArchiver{MaxCompressRatio: 1}
+	funcParams := params()
+	err = za.ExtractSource(context.Background(), NewReaderSource("test.rpm", io.MultiReader(f), fi.Size()), processingFunc, funcParams)
+	require.NoError(t, err)
+	ad := funcParams["archiveData"].(*ArchiveData)
+	assert.Equal(t, "./usr/share/doc/php-zstd-devel/tests/info.phpt", ad.Name)
+	rpmPkg := funcParams["rpmPkg"].(*RpmPkg)
+	assert.Equal(t, "php-zstd-devel", rpmPkg.Name)
+}
@@ -0,0 +1,225 @@
+package archive_extractor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+)
+
+var ErrUnknownSourceSize = errors.New("source size is unknown, compress ratio limit can't be computed")
+
+// ErrSpoolLimitReached is returned when a stream source has to be read again for the targets of its links,
+// but it is larger than the copy kept to read it again (see TarArchiver.MaxSpoolSize)
+var ErrSpoolLimitReached = archiver_errors.WithKind(archiver_errors.ErrLimitExceeded, errors.New("stream too large to be read again for the targets of its links"))
+
+// ErrSourceSizeExceeded is returned when a stream source copied to a temporary file is longer than its Size
+var ErrSourceSizeExceeded = archiver_errors.WithKind(archiver_errors.ErrLimitExceeded, errors.New("stream longer than the size of its source"))
+
+// ErrSpoolUnknownSize is returned when a stream source of unknown size has to be copied to a temporary file,
+// which zip and 7z archives need to be read at random offsets
+var ErrSpoolUnknownSize = errors.New("source size is unknown, stream can't be copied to a temporary file")
+
+// SourceArchiver is implemented by archivers that can extract archives which are not stored in a file,
+// for example objects read from blob storage or HTTP bodies.
+type SourceArchiver interface {
+	ExtractSource(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error
+}
+
+// Source is the content of an archive together with the details that are usually taken from the archive file.
+// A Source created by NewReaderSource can be extracted only once.
+type Source struct {
+	// Name is used to detect compressions that have no magic bytes by their extension
+	// and to name the entry of a decompressed file
+	Name string
+	// Size is the size of the archive in bytes, the MaxCompressRatio limit is computed from it.
+	// A negative size means the size is unknown, in that case MaxCompressRatio can't be used.
+	Size    int64
+	ModTime time.Time
+
+	reader   io.Reader
+	readerAt io.ReaderAt
+	// spoolLimit replaces Size as the limit of spool when it is not 0, a negative limit means there is none.
+	// It is set for the nested sources whose Size is not the size of their content.
+	spoolLimit int64
+}
+
+// NewReaderSource creates a source for streaming formats (tar, deb, rpm, rar and compressed files).
+// Random access formats (zip, 7z) spool the stream to a temporary file before extracting it,
+// they need its size to bound the copy.
+func NewReaderSource(name string, reader io.Reader, size int64) *Source {
+	return &Source{Name: name, Size: size, reader: reader}
+}
+
+// NewReaderAtSource creates a source that can be used by every archiver and extracted any number of times
+func NewReaderAtSource(name string, readerAt io.ReaderAt, size int64) *Source {
+	return &Source{Name: name, Size: size, readerAt: readerAt}
+}
+
+func openFileSource(path string) (*Source, *os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return &Source{Name: path, Size: fi.Size(), ModTime: fi.ModTime(), readerAt: f}, f, nil
+}
+
+func (s *Source) maxBytesLimit(maxCompressRatio int64) (int64, error) {
+	if maxCompressRatio == 0 {
+		return 0, nil
+	}
+	if s.Size < 0 {
+		return 0, ErrUnknownSourceSize
+	}
+	return s.Size * maxCompressRatio, nil
+}
+
+// stream returns the content of the source from its beginning
+func (s *Source) stream() io.Reader {
+	if s.readerAt != nil {
+		return io.NewSectionReader(s.readerAt, 0, s.Size)
+	}
+	return s.reader
+}
+
+// section returns a seekable view over the content of the source, spooling a stream to a temporary file if needed.
+// The returned cleanup function must be called once the section is no longer used.
+func (s *Source) section() (*io.SectionReader, func(), error) {
+	if s.readerAt != nil {
+		return io.NewSectionReader(s.readerAt, 0, s.Size), func() {}, nil
+	}
+	f, cleanup, err := s.spool()
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return io.NewSectionReader(f, 0, fi.Size()), cleanup, nil
+}
+
+// spool copies the content of the source to a temporary file which keeps the base name of the source,
+// so its extension can still be used. The returned cleanup function closes and removes the file.
+// A stream is copied up to its Size, ErrSourceSizeExceeded is returned when it is longer
+// and ErrSpoolUnknownSize when its size is unknown.
+func (s *Source) spool() (*os.File, func(), error) {
+	limit := s.Size
+	if s.spoolLimit != 0 {
+		limit = s.spoolLimit
+	}
+	if limit < 0 {
+		return nil, nil, ErrSpoolUnknownSize
+	}
+	f, err := os.CreateTemp("", "archive-extractor-*-"+filepath.Base(s.Name))
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	written, err := io.Copy(f, io.LimitReader(s.stream(), limit+1))
+	if err == nil && written > limit {
+		err = ErrSourceSizeExceeded
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return f, cleanup, nil
+}
+
+// sourceReplay reads a source once more after it was read. A stream is copied to a temporary file while it is read,
+// the file is created with the first bytes read and removed once the copy exceeds maxSize.
+type sourceReplay struct {
+	source *Source
+	stream io.Reader
+	copy   *replayWriter
+}
+
+// newSourceReplay creates the replay of source, a stream is not copied when maxSize is negative
+func newSourceReplay(source *Source, maxSize int64) *sourceReplay {
+	sr := &sourceReplay{source: source, stream: source.stream()}
+	if source.readerAt == nil {
+		sr.copy = &replayWriter{left: maxSize, dropped: maxSize < 0}
+		sr.stream = io.TeeReader(sr.stream, sr.copy)
+	}
+	return sr
+}
+
+// replay returns the content of the source from its beginning once the stream was read,
+// ErrSpoolLimitReached when the copy of the stream was dropped
+func (sr *sourceReplay) replay() (io.Reader, error) {
+	if sr.copy == nil {
+		return sr.source.stream(), nil
+	}
+	if !sr.copy.dropped {
+		// the end of the stream may not have been read
+		if _, err := io.Copy(io.Discard, sr.stream); err != nil {
+			return nil, err
+		}
+	}
+	if sr.copy.dropped {
+		return nil, ErrSpoolLimitReached
+	}
+	if sr.copy.file == nil {
+		return bytes.NewReader(nil), nil
+	}
+	size, err := sr.copy.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(sr.copy.file, 0, size), nil
+}
+
+func (sr *sourceReplay) close() {
+	if sr.copy != nil {
+		sr.copy.drop()
+	}
+}
+
+// replayWriter copies a stream to a temporary file until it exceeds the size it was given
+type replayWriter struct {
+	file    *os.File
+	left    int64
+	dropped bool
+}
+
+func (rw *replayWriter) Write(p []byte) (int, error) {
+	if rw.dropped {
+		return len(p), nil
+	}
+	if int64(len(p)) > rw.left {
+		rw.drop()
+		return len(p), nil
+	}
+	if rw.file == nil {
+		var err error
+		if rw.file, err = os.CreateTemp("", "archive-extractor-*"); err != nil {
+			return 0, err
+		}
+	}
+	rw.left -= int64(len(p))
+	return rw.file.Write(p)
+}
+
+func (rw *replayWriter) drop() {
+	rw.dropped = true
+	if rw.file != nil {
+		_ = rw.file.Close()
+		_ = os.Remove(rw.file.Name())
+		rw.file = nil
+	}
+}
@@ -7,16 +7,54 @@ import (
 type TarArchiver struct {
 	MaxCompressRatio   int64
 	MaxNumberOfEntries int
+	EntryOptions
+	// HardlinkAliases reports the hardlinks to a file once the archive is read, as a single EntryHardlink entry without
+	// content having the path of the file as LinkTarget and the paths of the hardlinks as Aliases.
+	// By default the content of the file is reported again under the path of every hardlink.
+	HardlinkAliases bool
+	// MaxSpoolSize bounds the temporary file holding the content the processing function read from the regular files
+	// until the links pointing to them are known, DefaultMaxSpoolSize when 0 and no spool when negative.
+	// The link targets that aren't in it are extracted again from the archive. A stream Source is copied to a temporary
+	// file of at most MaxSpoolSize bytes too while it is read, ErrSpoolLimitReached is returned when it is larger
+	// and the link targets must be extracted again.
+	MaxSpoolSize int64
 }
 
 func (ta TarArchiver) ExtractArchive(path string, processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
-	ctx := context.Background()
-	maxBytesLimit, err := maxBytesLimit(path, ta.MaxCompressRatio)
+	return ta.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (ta TarArchiver) ExtractArchiveContext(ctx context.Context, path string, processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ta.ExtractSource(ctx, source, processingFunc, params)
+}
+
+func (ta TarArchiver) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := ta.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+func (ta TarArchiver) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, ta.EntryOptions, FoldersSkip)
+	if err != nil {
+		return result, err
+	}
+	return result, result.finish(ta.extractEntries(ctx, source, processEntry))
+}
+
+func (ta TarArchiver) extractEntries(ctx context.Context, source *Source, processEntry processEntryFunc) error {
+	maxBytesLimit, err := source.maxBytesLimit(ta.MaxCompressRatio)
 	if err != nil {
 		return err
 	}
-	provider := LimitAggregatingReadCloserProvider{
+	provider := &LimitAggregatingReadCloserProvider{
 		Limit: maxBytesLimit,
 	}
-	return extractWithSymlinks(ctx, path, ta.MaxNumberOfEntries, provider, processingFunc, params)
+	links := newLinkExtraction(ta.HardlinkAliases, ta.MaxSpoolSize, ta.EntryOptions, provider, processEntry)
+	return extractTar(ctx, source, ta.MaxNumberOfEntries, links, provider)
 }
@@ -3,10 +3,16 @@
 package archive_extractor
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -105,3 +111,190 @@ func TestTarArchiver_TarLz(t *testing.T) {
 	assert.Equal(t, ad.IsFolder, false)
 	assert.Equal(t, ad.Size, int64(41123))
 }
+
+func TestTarArchiverContextCanceled(t *testing.T) {
+	za := &TarArchiver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := za.ExtractArchiveContext(ctx, "./fixtures/testmanylarge.tar.gz", processingReadingFunc, params())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTarArchiverContextDeadlineExceeded(t *testing.T) {
+	za := &TarArchiver{}
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	err := za.ExtractArchiveContext(ctx, "./fixtures/testmanylarge.tar.gz", processingReadingFunc, params())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestTarArchiverExtractSourceStream(t *testing.T) {
+	f, err := os.Open("./fixtures/test.tar.gz")
+	require.NoError(t, err)
+	defer f.Close()
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	za := &TarArchiver{MaxCompressRatio: 100}
+	funcParams := params()
+	source := NewReaderSource("test.tar.gz", io.MultiReader(f), fi.Size())
+	err = za.ExtractSource(context.Background(), source, processingFunc, funcParams)
+	require.NoError(t, err)
+	ad := funcParams["archiveData"].(*ArchiveData)
+	assert.Equal(t, "logRotator-1.0/log_rotator.go", ad.Name)
+	assert.Equal(t, int64(3685), ad.Size)
+}
+
+func TestTarArchiverMaxEntrySizeOption(t *testing.T) {
+	err := Extract("./fixtures/test.tar.gz", processingReadingFunc, params(), WithMaxEntrySize(10))
+	assert.True(t, IsErrEntryLimitReached(err))
+	assert.NoError(t, Extract("./fixtures/test.tar.gz", processingReadingFunc, params(), WithMaxEntryCompressRatio(10)))
+}
+
+// tarWithLinks writes a tar file holding a regular file, a symlink and two hardlinks to it,
+// and a hardlink whose target isn't in the archive
+func tarWithLinks(t *testing.T) string {
+	return writeTestFile(t, "links.tar", tarBytes(t,
+		testEntry{name: "lib/libfoo.so.1", content: []byte("shared content"), mode: 0755},
+		symlinkEntry("lib/libfoo.so", "libfoo.so.1"),
+		testEntry{name: "lib/libfoo-copy.so", mode: 0755, link: "lib/libfoo.so.1"},
+		testEntry{name: "bin/foo", mode: 0755, link: "./lib/libfoo.so.1"},
+		testEntry{name: "bin/missing", mode: 0755, link: "lib/missing"}))
+}
+
+func TestTarArchiverHardlinks(t *testing.T) {
+	headers := collect(t, TarArchiver{}, tarWithLinks(t)).byPath()
+	require.Len(t, headers, 5)
+	for _, name := range []string{"lib/libfoo.so.1", "lib/libfoo.so", "lib/libfoo-copy.so", "bin/foo"} {
+		content, err := io.ReadAll(headers[name].ArchiveReader)
+		require.NoError(t, err)
+		assert.Equal(t, "shared content", string(content), name)
+		assert.Equal(t, int64(len(content)), headers[name].Size, name)
+	}
+	assert.Equal(t, EntryRegular, headers["lib/libfoo.so.1"].Type)
+	assert.Equal(t, EntryHardlink, headers["bin/foo"].Type)
+	assert.Equal(t, "lib/libfoo.so.1", headers["bin/foo"].LinkTarget)
+
+	// the hardlinks whose target isn't in the archive are reported as is
+	missing := headers["bin/missing"]
+	assert.Equal(t, EntryHardlink, missing.Type)
+	assert.Equal(t, "lib/missing", missing.LinkTarget)
+	assert.Zero(t, missing.Size)
+}
+
+func TestTarArchiverHardlinkAliases(t *testing.T) {
+	headers := collect(t, TarArchiver{HardlinkAliases: true}, tarWithLinks(t)).byPath()
+	require.Len(t, headers, 4)
+	content, err := io.ReadAll(headers["lib/libfoo.so.1"].ArchiveReader)
+	require.NoError(t, err)
+	assert.Equal(t, "shared content", string(content))
+	// the hardlinks to the file are reported once, under the path of the first of them
+	link := headers["lib/libfoo-copy.so"]
+	assert.Equal(t, EntryHardlink, link.Type)
+	assert.Equal(t, "lib/libfoo.so.1", link.LinkTarget)
+	assert.Equal(t, []string{"lib/libfoo-copy.so", "bin/foo"}, link.Aliases)
+	assert.Zero(t, link.Size)
+	content, err = io.ReadAll(link.ArchiveReader)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+	assert.NotContains(t, headers, "bin/foo")
+	assert.Empty(t, headers["bin/missing"].Aliases)
+
+	// the hardlinks the filter doesn't select are not aliases
+	filtered := collect(t, TarArchiver{EntryOptions: EntryOptions{Filter: EntryFilter{Include: []string{"bin/*"}}}, HardlinkAliases: true}, tarWithLinks(t)).byPath()
+	require.Contains(t, filtered, "bin/foo")
+	assert.Equal(t, []string{"bin/foo"}, filtered["bin/foo"].Aliases)
+}
+
+func TestTarArchiverHardlinksCountedByLimit(t *testing.T) {
+	// the content reported under every hardlink is counted by the compression ratio limit
+	entries := []testEntry{{name: "target", content: bytes.Repeat([]byte("a"), 5000)}}
+	for i := 0; i < 10; i++ {
+		entries = append(entries, testEntry{name: fmt.Sprintf("link%d", i), link: "target"})
+	}
+	path := writeTestFile(t, "hardlinks.tar", tarBytes(t, entries...))
+
+	err := TarArchiver{MaxCompressRatio: 2}.ExtractArchive(path, processingReadingFunc, params())
+	assert.True(t, IsErrCompressLimitReached(err))
+	assert.NoError(t, TarArchiver{MaxCompressRatio: 2, HardlinkAliases: true}.ExtractArchive(path, processingReadingFunc, params()))
+}
+
+// countingReaderAt counts the bytes read from the archive
+type countingReaderAt struct {
+	readerAt io.ReaderAt
+	read     int64
+}
+
+func (cr *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := cr.readerAt.ReadAt(p, off)
+	cr.read += int64(n)
+	return n, err
+}
+
+func TestTarArchiverSinglePass(t *testing.T) {
+	data, err := os.ReadFile(tarWithLinks(t))
+	require.NoError(t, err)
+	expected := collect(t, TarArchiver{}, tarWithLinks(t)).byPath()
+
+	for _, maxSpoolSize := range []int64{0, -1} {
+		readerAt := &countingReaderAt{readerAt: bytes.NewReader(data)}
+		source := NewReaderAtSource("links.tar", readerAt, int64(len(data)))
+		contents := map[string]string{}
+		_, err = TarArchiver{MaxSpoolSize: maxSpoolSize}.ExtractWithResult(context.Background(), source, func(header *ArchiveHeader) error {
+			content, err := io.ReadAll(header.ArchiveReader)
+			contents[header.Name] = string(content)
+			return err
+		})
+		require.NoError(t, err)
+		assert.Len(t, contents, len(expected))
+		assert.Equal(t, "shared content", contents["bin/foo"])
+		if maxSpoolSize == 0 {
+			// the spooled link targets are not read again
+			assert.Equal(t, int64(len(data)), readerAt.read)
+		} else {
+			assert.Greater(t, readerAt.read, int64(len(data)))
+		}
+	}
+
+	// the link targets which aren't read are read again from a copy of the stream, bounded by MaxSpoolSize
+	skipTarget := func(contents map[string]string) func(*ArchiveHeader) error {
+		return func(header *ArchiveHeader) error {
+			if header.Name == "lib/libfoo.so.1" {
+				return nil
+			}
+			content, err := io.ReadAll(header.ArchiveReader)
+			contents[header.Name] = string(content)
+			return err
+		}
+	}
+	contents := map[string]string{}
+	source := NewReaderSource("links.tar", bytes.NewReader(data), int64(len(data)))
+	_, err = TarArchiver{}.ExtractWithResult(context.Background(), source, skipTarget(contents))
+	require.NoError(t, err)
+	assert.Equal(t, "shared content", contents["lib/libfoo.so"])
+	assert.Equal(t, "shared content", contents["lib/libfoo-copy.so"])
+	for _, maxSpoolSize := range []int64{-1, int64(len(data)) - 1} {
+		source = NewReaderSource("links.tar", bytes.NewReader(data), int64(len(data)))
+		_, err = TarArchiver{MaxSpoolSize: maxSpoolSize}.ExtractWithResult(context.Background(), source, skipTarget(map[string]string{}))
+		assert.ErrorIs(t, err, ErrSpoolLimitReached, maxSpoolSize)
+		assert.ErrorIs(t, err, archiver_errors.ErrLimitExceeded, maxSpoolSize)
+	}
+}
+
+func TestTarArchiverStreamWithoutLinks(t *testing.T) {
+	// a stream is read once when its links don't need it, even when it doesn't fit in the spool
+	data := gzipBytes(t, tarBytes(t, testEntry{name: "large.txt", content: bytes.Repeat([]byte("a"), 100000)}))
+	source := NewReaderSource("large.tar.gz", bytes.NewReader(data), int64(len(data)))
+	_, err := TarArchiver{MaxSpoolSize: 10}.ExtractWithResult(context.Background(), source, func(header *ArchiveHeader) error {
+		_, err := io.Copy(io.Discard, header.ArchiveReader)
+		return err
+	})
+	assert.NoError(t, err)
+}
+
+func TestTarArchiverListingNotCountedByLimit(t *testing.T) {
+	// the content the processing function doesn't read is not read for the links either
+	path := tarGzFile(t, "large.tar.gz", testEntry{name: "large.txt", content: bytes.Repeat([]byte("a"), 100000)})
+	assert.NoError(t, TarArchiver{MaxCompressRatio: 2}.ExtractArchive(path, processingFunc, params()))
+	err := TarArchiver{MaxCompressRatio: 2}.ExtractArchive(path, processingReadingFunc, params())
+	assert.True(t, IsErrCompressLimitReached(err))
+}
@@ -0,0 +1,95 @@
+package archive_extractor
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/mholt/archives"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/jfrog/go-archive-extractor/compression"
+	"github.com/jfrog/go-archive-extractor/utils"
+)
+
+// extractTar extracts a tar archive in a single pass, its links are reported once the whole archive is read (see linkExtraction)
+func extractTar(ctx context.Context, source *Source, maxNumberOfEntries int, links *linkExtraction, provider *LimitAggregatingReadCloserProvider) error {
+	replay := newSourceReplay(source, links.maxSpoolSize)
+	defer replay.close()
+	entriesCount := 0
+	var multiErrors *archiver_errors.MultiError
+	processEntry := links.process(ctx)
+	err := readTar(ctx, source.Name, replay.stream, func(ctx context.Context, fileInfo archives.FileInfo) error {
+		if maxNumberOfEntries != 0 && entriesCount >= maxNumberOfEntries {
+			return ErrTooManyEntries
+		}
+		entriesCount++
+		return processTarEntry(ctx, fileInfo, provider, links.selects, func(header *ArchiveHeader, err error) error {
+			if err != nil {
+				multiErrors = archiver_errors.Append(multiErrors, archiver_errors.NewArchiverExtractorError(cleanArchivePath(fileInfo.NameInArchive), classifyError(err)))
+				return nil
+			}
+			return processEntry(header)
+		})
+	})
+	//multi error can be skipped or not skipped by caller, therefore we distinguish between err and multiErrors
+	if err == nil && multiErrors != nil {
+		err = multiErrors
+	}
+	return links.finish(ctx, err, func(selectEntry entrySelector, processEntry processEntryFunc) error {
+		reader, err := replay.replay()
+		if err != nil {
+			return err
+		}
+		return readTar(ctx, source.Name, reader, func(ctx context.Context, fileInfo archives.FileInfo) error {
+			return processTarEntry(ctx, fileInfo, provider, selectEntry, func(header *ArchiveHeader, err error) error {
+				if err != nil {
+					return nil
+				}
+				return processEntry(header)
+			})
+		})
+	})
+}
+
+func readTar(ctx context.Context, name string, reader io.Reader, handleFile archives.FileHandler) error {
+	arcReader, _, err := compression.NewReaderFrom(reader, name, compression.WithContext(ctx))
+	if compression.IsGetReaderError(err) {
+		return archiver_errors.New(classifyError(err))
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		arcReader.Close()
+	}()
+	return archives.Tar{}.Extract(ctx, arcReader, handleFile)
+}
+
+// processTarEntry opens the entry and passes its header, or the error opening it, to processHeader.
+// The entries selectEntry doesn't select are skipped.
+func processTarEntry(ctx context.Context, fileInfo archives.FileInfo, provider *LimitAggregatingReadCloserProvider,
+	selectEntry entrySelector, processHeader func(*ArchiveHeader, error) error) error {
+	archiveHeader := NewArchiveHeader(nil, cleanArchivePath(fileInfo.NameInArchive), fileInfo.ModTime().Unix(), fileInfo.Size())
+	archiveHeader.RawName = fileInfo.NameInArchive
+	archiveHeader.setFileInfo(fileInfo)
+	if !selectEntry(archiveHeader) {
+		return nil
+	}
+	if archiveHeader.IsFolder {
+		// the folders have no content to open
+		archiveHeader.ArchiveReader = provider.CreateLimitAggregatingReadCloser(strings.NewReader(""))
+		return processHeader(archiveHeader, nil)
+	}
+	file, err := fileInfo.Open()
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+	}()
+	if err != nil {
+		return processHeader(nil, err)
+	}
+	archiveHeader.ArchiveReader = provider.CreateLimitAggregatingReadCloser(utils.NewContextReader(ctx, file))
+	return processHeader(archiveHeader, nil)
+}
@@ -3,26 +3,46 @@ package archive_extractor
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+	"github.com/jfrog/go-archive-extractor/utils"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 )
 
 const fileHeaderSignatureString = "PK\x03\x04"
 const zoneInfoFileHeaderSignatureString = "\x23\x20\x76\x65\x72\x73\x69\x6F\x6E"
 const zoneInfoErrMsg = "zone info file found instead of zip"
 
+// errNoZipFile is returned when neither the file nor a part of it is a zip file
+var errNoZipFile = archiver_errors.WithKind(archiver_errors.ErrNotThisFormat, errors.New("No zip file found"))
+
 type ZoneInfoFileError struct{}
 
 func (e *ZoneInfoFileError) Error() string {
 	return zoneInfoErrMsg
 }
 
+func (e *ZoneInfoFileError) Is(target error) bool {
+	return target == archiver_errors.ErrNotThisFormat
+}
+
 type ZipArchiver struct {
 	MaxCompressRatio   int64
 	MaxNumberOfEntries int
+	EntryOptions
+	// Password decrypts the ZipCrypto and WinZip AES encrypted entries,
+	// PasswordProvider is called when it is empty and the first encrypted entry is met
+	Password         string
+	PasswordProvider PasswordProvider
+	// Workers is the number of entries processed concurrently, processingFunc is called from several goroutines
+	// when it is above 1. The links of the archive are still reported once the other entries are processed.
+	// The other archivers, SevenZipArchiver included, have no Workers and process their entries one at a time.
+	Workers int
 }
 
 type ZipReadCloser struct {
@@ -32,39 +52,81 @@ type ZipReadCloser struct {
 
 func (za ZipArchiver) ExtractArchive(path string,
 	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
-	maxBytesLimit, err := maxBytesLimit(path, za.MaxCompressRatio)
+	return za.ExtractArchiveContext(context.Background(), path, processingFunc, params)
+}
+
+func (za ZipArchiver) ExtractArchiveContext(ctx context.Context, path string,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	source, f, err := openFileSource(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return za.ExtractSource(ctx, source, processingFunc, params)
+}
+
+func (za ZipArchiver) ExtractSource(ctx context.Context, source *Source,
+	processingFunc func(*ArchiveHeader, map[string]interface{}) error, params map[string]interface{}) error {
+	_, err := za.ExtractWithResult(ctx, source, withParams(processingFunc, params))
+	return err
+}
+
+func (za ZipArchiver) ExtractWithResult(ctx context.Context, source *Source, processingFunc func(*ArchiveHeader) error) (*ExtractResult, error) {
+	result, processEntry, err := newExtraction(processingFunc, za.EntryOptions, FoldersExplicit)
+	if err != nil {
+		return result, err
+	}
+	return result, result.finish(za.extractEntries(ctx, source, processEntry))
+}
+
+func (za ZipArchiver) extractEntries(ctx context.Context, source *Source, processEntry processEntryFunc) error {
+	maxBytesLimit, err := source.maxBytesLimit(za.MaxCompressRatio)
 	if err != nil {
 		return err
 	}
 	rcProvider := LimitAggregatingReadCloserProvider{Limit: maxBytesLimit}
-	r, err := openZipReader(path)
+	section, cleanup, err := source.section()
 	if err != nil {
 		return err
 	}
-	defer r.Close()
-	var multiArchiveErr error
+	defer cleanup()
+	r, err := initZipReader(section, section.Size())
+	if err != nil {
+		return err
+	}
+	if err = checkZipBomb(r, section.Size()); err != nil {
+		return err
+	}
 	if za.MaxNumberOfEntries > 0 && len(r.File) > za.MaxNumberOfEntries {
 		return ErrTooManyEntries
 	}
+	password := newArchivePassword(source.Name, za.Password, za.PasswordProvider)
+	// the link targets are opened again rather than spooled
+	links := newLinkExtraction(false, -1, za.EntryOptions, &rcProvider, processEntry)
+	err = za.processEntries(ctx, source, r, password, &rcProvider, za.Workers, links.selects, links.process(ctx))
+	return links.finish(ctx, err, func(selectEntry entrySelector, processEntry processEntryFunc) error {
+		return za.processEntries(ctx, source, r, password, &rcProvider, 1, selectEntry, processEntry)
+	})
+}
+
+func (za ZipArchiver) processEntries(ctx context.Context, source *Source, r *zip.Reader, password *archivePassword,
+	rcProvider *LimitAggregatingReadCloserProvider, workers int, selectEntry entrySelector, processEntry processEntryFunc) error {
+	if workers > 1 {
+		return processEntriesConcurrently(ctx, source, r, password, rcProvider, workers, selectEntry, processEntry)
+	}
+	var multiArchiveErr error
 	for _, archiveEntry := range r.File {
-		rc, err := archiveEntry.Open()
-		if err != nil {
-			if rc != nil {
-				rc.Close()
-			}
-			multiArchiveErr = archiver_errors.Append(multiArchiveErr, fmt.Errorf("failed to open %s: %v", path, err))
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		openErr, err := processZipEntry(ctx, archiveEntry, password, rcProvider, selectEntry, processEntry)
+		if openErr != nil {
+			multiArchiveErr = archiver_errors.Append(multiArchiveErr, fmt.Errorf("failed to open %s: %w", source.Name, classifyError(openErr)))
 			continue
 		}
-		countingReadCloser := rcProvider.CreateLimitAggregatingReadCloser(rc)
-		archiveHeader := NewArchiveHeader(countingReadCloser, archiveEntry.Name, archiveEntry.ModTime().Unix(), archiveEntry.FileInfo().Size())
-		err = processingFunc(archiveHeader, params)
 		if err != nil {
-			if rc != nil {
-				rc.Close()
-			}
 			return err
 		}
-		rc.Close()
 	}
 	if multiArchiveErr != nil {
 		return archiver_errors.New(multiArchiveErr)
@@ -72,6 +134,80 @@ func (za ZipArchiver) ExtractArchive(path string,
 	return nil
 }
 
+// processEntriesConcurrently processes the entries with the given number of goroutines, calling processEntry concurrently.
+// Once an entry fails no more entries are started, so that the error returned is the one of the first failing entry in the
+// order of the archive, as when the entries are processed sequentially. The entries that can't be opened are reported in that order too.
+func processEntriesConcurrently(ctx context.Context, source *Source, r *zip.Reader, password *archivePassword,
+	rcProvider *LimitAggregatingReadCloserProvider, workers int, selectEntry entrySelector, processEntry processEntryFunc) error {
+	openErrs := make([]error, len(r.File))
+	errs := make([]error, len(r.File))
+	var failed atomic.Bool
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range indexes {
+				openErrs[i], errs[i] = processZipEntry(ctx, r.File[i], password, rcProvider, selectEntry, processEntry)
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		})
+	}
+	for i := range r.File {
+		if failed.Load() || ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var multiArchiveErr error
+	for i := range r.File {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		if openErrs[i] != nil {
+			multiArchiveErr = archiver_errors.Append(multiArchiveErr, fmt.Errorf("failed to open %s: %w", source.Name, classifyError(openErrs[i])))
+		}
+	}
+	if multiArchiveErr != nil {
+		return archiver_errors.New(multiArchiveErr)
+	}
+	return nil
+}
+
+// processZipEntry opens the entry and passes its header to processEntry, openErr is the error opening it.
+// The entries selectEntry doesn't select are not opened, all of them are when it is nil.
+func processZipEntry(ctx context.Context, archiveEntry *zip.File, password *archivePassword,
+	rcProvider *LimitAggregatingReadCloserProvider, selectEntry entrySelector, processEntry processEntryFunc) (openErr error, err error) {
+	archiveHeader := NewArchiveHeader(nil, archiveEntry.Name, archiveEntry.ModTime().Unix(), archiveEntry.FileInfo().Size())
+	archiveHeader.setMode(archiveEntry.Mode())
+	archiveHeader.CompressedSize = int64(archiveEntry.CompressedSize64)
+	// ModTime keeps the MS-DOS time it always had, Modified also reads the extended timestamp field
+	archiveHeader.ModTimeNs = unixNano(archiveEntry.Modified)
+	archiveHeader.Uid, archiveHeader.Gid = zipOwner(archiveEntry.Extra)
+	archiveHeader.Encrypted = archiveEntry.Flags&zipFlagEncrypted != 0
+	if selectEntry != nil && !selectEntry(archiveHeader) {
+		return nil, nil
+	}
+	rc, err := openZipEntry(archiveEntry, password)
+	if rc != nil {
+		defer rc.Close()
+	}
+	if err != nil {
+		return err, nil
+	}
+	archiveHeader.ArchiveReader = rcProvider.CreateLimitAggregatingReadCloser(utils.NewContextReader(ctx, rc))
+	if err = archiveHeader.readLinkTarget(); err != nil {
+		return nil, err
+	}
+	return nil, processEntry(archiveHeader)
+}
+
 func openZipReader(name string) (*ZipReadCloser, error) {
 	f, err := os.Open(name)
 	if err != nil {
@@ -83,6 +219,9 @@ func openZipReader(name string) (*ZipReadCloser, error) {
 		return nil, err
 	}
 	r, err := initZipReader(f, fi.Size())
+	if err == nil {
+		err = checkZipBomb(r, fi.Size())
+	}
 	if err != nil {
 		f.Close()
 		return nil, err
@@ -131,7 +270,7 @@ func initZipReader(r io.ReaderAt, size int64) (*zip.Reader, error) {
 			break
 		}
 	}
-	return nil, errors.New("No zip file found")
+	return nil, errNoZipFile
 }
 
 // The tz database is also known as tzdata, the zoneinfo database or IANA time zone database.
@@ -6,6 +6,8 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
@@ -13,7 +15,9 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestZipUnexpectedEofArchiver(t *testing.T) {
@@ -191,3 +195,173 @@ func TestZipArchiver_initZipReader_dummySignaturesBeforeFile(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestZipArchiverContextCanceled(t *testing.T) {
+	za := &ZipArchiver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := za.ExtractArchiveContext(ctx, "./fixtures/testwithmanyfiles.zip", processingReadingFunc, params())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestZipArchiverContextCanceledMidEntry(t *testing.T) {
+	za := &ZipArchiver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	processor := func(header *ArchiveHeader, params map[string]interface{}) error {
+		cancel()
+		_, err := io.Copy(io.Discard, header.ArchiveReader)
+		return err
+	}
+	err := za.ExtractArchiveContext(ctx, "./fixtures/testwithcontent.zip", processor, params())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestZipArchiverExtractSourceReaderAt(t *testing.T) {
+	content, err := os.ReadFile("./fixtures/testwithcontent.zip")
+	require.NoError(t, err)
+	za := &ZipArchiver{MaxCompressRatio: 1}
+	funcParams := params()
+	source := NewReaderAtSource("testwithcontent.zip", bytes.NewReader(content), int64(len(content)))
+	err = za.ExtractSource(context.Background(), source, processingReadingFunc, funcParams)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), funcParams["read"])
+}
+
+func TestZipArchiverExtractSourceStream(t *testing.T) {
+	f, err := os.Open("./fixtures/testwithsinglelargefile.zip")
+	require.NoError(t, err)
+	defer f.Close()
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	za := &ZipArchiver{MaxCompressRatio: 1}
+	// io.MultiReader hides the io.ReaderAt implementation of the file
+	source := NewReaderSource("testwithsinglelargefile.zip", io.MultiReader(f), fi.Size())
+	err = za.ExtractSource(context.Background(), source, processingReadingFunc, params())
+	assert.True(t, IsErrCompressLimitReached(err))
+}
+
+func TestZipArchiverExtractSourceUnknownSize(t *testing.T) {
+	f, err := os.Open("./fixtures/testwithcontent.zip")
+	require.NoError(t, err)
+	defer f.Close()
+	za := &ZipArchiver{MaxCompressRatio: 1}
+	err = za.ExtractSource(context.Background(), NewReaderSource("testwithcontent.zip", f, -1), processingReadingFunc, params())
+	assert.ErrorIs(t, err, ErrUnknownSourceSize)
+	err = ZipArchiver{}.ExtractSource(context.Background(), NewReaderSource("testwithcontent.zip", f, -1), processingReadingFunc, params())
+	assert.ErrorIs(t, err, ErrSpoolUnknownSize)
+}
+
+func TestZipArchiverExtractSourceLongerThanSize(t *testing.T) {
+	content := zipBytes(t, fileEntry("a.txt", "content"))
+	source := NewReaderSource("a.zip", bytes.NewReader(content), int64(len(content))-1)
+	err := ZipArchiver{}.ExtractSource(context.Background(), source, processingReadingFunc, params())
+	assert.ErrorIs(t, err, ErrSourceSizeExceeded)
+}
+
+func TestZipArchiverMaxEntryCompressRatio(t *testing.T) {
+	// the archive as a whole stays under MaxCompressRatio thanks to its incompressible entry
+	random := make([]byte, 1<<20)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	data := zipBytes(t, testEntry{name: "random.bin", content: random}, testEntry{name: "zeros.bin", content: make([]byte, 1<<20)})
+	source := NewReaderAtSource("entries.zip", bytes.NewReader(data), int64(len(data)))
+	za := &ZipArchiver{MaxCompressRatio: 10, EntryOptions: EntryOptions{MaxEntryCompressRatio: 100}}
+	err = za.ExtractSource(context.Background(), source, processingReadingFunc, params())
+	var entryErr *ErrEntryLimitReached
+	require.ErrorAs(t, err, &entryErr)
+	assert.Equal(t, "zeros.bin", entryErr.Name)
+	assert.False(t, IsErrCompressLimitReached(err))
+
+	za = &ZipArchiver{MaxCompressRatio: 10}
+	assert.NoError(t, za.ExtractSource(context.Background(), source, processingReadingFunc, params()))
+}
+
+func TestZipArchiverMaxEntrySize(t *testing.T) {
+	za := &ZipArchiver{EntryOptions: EntryOptions{MaxEntrySize: 12}}
+	err := za.ExtractArchive("./fixtures/testwithcontent.zip", processingReadingFunc, params())
+	var entryErr *ErrEntryLimitReached
+	require.ErrorAs(t, err, &entryErr)
+	assert.Equal(t, ErrEntryLimitReached{Name: "test.txt", SizeLimit: 12, CurrSize: 13}, *entryErr)
+
+	za = &ZipArchiver{EntryOptions: EntryOptions{MaxEntrySize: 13}}
+	assert.NoError(t, za.ExtractArchive("./fixtures/testwithcontent.zip", processingReadingFunc, params()))
+}
+
+func TestZipArchiverWorkers(t *testing.T) {
+	var entries []testEntry
+	for i := range 50 {
+		entries = append(entries, testEntry{name: fmt.Sprintf("file%d.txt", i), content: []byte(fmt.Sprintf("content %d", i))})
+	}
+	data := zipBytes(t, entries...)
+	source := NewReaderAtSource("entries.zip", bytes.NewReader(data), int64(len(data)))
+	var mutex sync.Mutex
+	contents := map[string]string{}
+	// the first two entries wait for each other, so they must be processed concurrently
+	var firstEntries sync.WaitGroup
+	firstEntries.Add(2)
+	bothStarted := make(chan struct{})
+	go func() {
+		firstEntries.Wait()
+		close(bothStarted)
+	}()
+	za := &ZipArchiver{Workers: 4}
+	result, err := za.ExtractWithResult(context.Background(), source, func(header *ArchiveHeader) error {
+		if header.Name == "file0.txt" || header.Name == "file1.txt" {
+			firstEntries.Done()
+			select {
+			case <-bothStarted:
+			case <-time.After(10 * time.Second):
+				return errors.New("the entries are not processed concurrently")
+			}
+		}
+		content, err := io.ReadAll(header.ArchiveReader)
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		contents[header.Name] = string(content)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, contents, 50)
+	assert.Equal(t, "content 42", contents["file42.txt"])
+	assert.Equal(t, 50, result.EntriesCount)
+	var total int64
+	for _, entry := range entries {
+		total += int64(len(entry.content))
+	}
+	assert.Equal(t, total, result.BytesRead)
+}
+
+func TestZipArchiverWorkersFirstError(t *testing.T) {
+	var entries []testEntry
+	for i := range 20 {
+		entries = append(entries, testEntry{name: fmt.Sprintf("file%d.txt", i), content: []byte("content")})
+	}
+	data := zipBytes(t, entries...)
+	source := NewReaderAtSource("entries.zip", bytes.NewReader(data), int64(len(data)))
+	za := &ZipArchiver{Workers: 8}
+	err := za.ExtractSource(context.Background(), source, func(header *ArchiveHeader, params map[string]interface{}) error {
+		switch header.Name {
+		case "file3.txt":
+			// fails after the later entry
+			time.Sleep(50 * time.Millisecond)
+			return errors.New("file3 failed")
+		case "file5.txt":
+			return errors.New("file5 failed")
+		}
+		return nil
+	}, params())
+	assert.EqualError(t, err, "file3 failed")
+}
+
+func TestZipArchiverWorkersAggregationCauseError(t *testing.T) {
+	za := &ZipArchiver{MaxCompressRatio: 1, Workers: 4}
+	err := za.ExtractArchive("./fixtures/testmanyfileswithcontent.zip", func(header *ArchiveHeader, params map[string]interface{}) error {
+		_, err := io.Copy(io.Discard, header.ArchiveReader)
+		return err
+	}, params())
+	assert.True(t, IsErrCompressLimitReached(err))
+}
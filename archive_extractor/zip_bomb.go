@@ -0,0 +1,115 @@
+package archive_extractor
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jfrog/go-archive-extractor/archive_extractor/archiver_errors"
+)
+
+const (
+	// centralDirectoryHeaderLen and localFileHeaderLen are the sizes of the zip headers without their variable length fields
+	centralDirectoryHeaderLen = 46
+	localFileHeaderLen        = 30
+	// maxDeflateRatio is the best compression ratio deflate can achieve
+	maxDeflateRatio = 1032
+)
+
+type ZipBombReason string
+
+const (
+	// ZipBombTooManyEntries means the central directory declares more entries than the file can hold
+	ZipBombTooManyEntries ZipBombReason = "more entries than the file can hold"
+	// ZipBombSharedData means several entries point at the same data
+	ZipBombSharedData ZipBombReason = "data shared with another entry"
+	// ZipBombOverlappingEntries means the data of an entry overlaps the data of another entry
+	ZipBombOverlappingEntries ZipBombReason = "data overlapping another entry"
+	// ZipBombDeclaredSize means the declared sizes of an entry can't be achieved by its compression method
+	ZipBombDeclaredSize ZipBombReason = "declared size beyond what the file can hold"
+)
+
+// ErrZipBomb is returned by ZipArchiver when the structure of the zip file is built to expand far beyond its size.
+// It is detected from the central directory and the local headers, before any entry is decompressed.
+type ErrZipBomb struct {
+	// Name is the entry the bomb was detected at, empty for ZipBombTooManyEntries
+	Name   string
+	Reason ZipBombReason
+}
+
+func IsErrZipBomb(err error) bool {
+	var errZipBomb *ErrZipBomb
+	return errors.As(err, &errZipBomb)
+}
+
+func (e *ErrZipBomb) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("zip bomb detected: %s", e.Reason)
+	}
+	return fmt.Sprintf("zip bomb detected at %s: %s", e.Name, e.Reason)
+}
+
+// Is makes the zip bombs match archiver_errors.ErrLimitExceeded, like the archives exceeding MaxCompressRatio
+func (e *ErrZipBomb) Is(target error) bool {
+	return target == archiver_errors.ErrLimitExceeded
+}
+
+type zipDataRange struct {
+	name  string
+	start int64
+	end   int64
+}
+
+// checkZipBomb rejects zip files whose entries overlap or share their data, and whose
+// declared sizes or entries count can't fit in size bytes
+func checkZipBomb(r *zip.Reader, size int64) error {
+	// every entry has its own central directory header and local header, unless the entries share their data.
+	// The extra fields of the local headers may be shorter than the central ones, so only their fixed part is counted.
+	var minSize int64
+	for _, file := range r.File {
+		minSize += centralDirectoryHeaderLen + int64(len(file.Name)+len(file.Extra)+len(file.Comment)) + localFileHeaderLen
+	}
+	if minSize > size {
+		return &ErrZipBomb{Reason: ZipBombTooManyEntries}
+	}
+	ranges := make([]zipDataRange, 0, len(r.File))
+	for _, file := range r.File {
+		if file.CompressedSize64 > uint64(size) || !declaredSizeAchievable(file) {
+			return &ErrZipBomb{Name: file.Name, Reason: ZipBombDeclaredSize}
+		}
+		start, err := file.DataOffset()
+		if err != nil {
+			// the entry can't be read anyway, opening it reports the error
+			continue
+		}
+		ranges = append(ranges, zipDataRange{name: file.Name, start: start, end: start + int64(file.CompressedSize64)})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	// end is the furthest end of the ranges already checked, a long range can overlap several following ones
+	var end int64
+	for i, dataRange := range ranges {
+		if i > 0 && dataRange.start == ranges[i-1].start {
+			return &ErrZipBomb{Name: dataRange.name, Reason: ZipBombSharedData}
+		}
+		if i > 0 && dataRange.start < end {
+			return &ErrZipBomb{Name: dataRange.name, Reason: ZipBombOverlappingEntries}
+		}
+		end = max(end, dataRange.end)
+	}
+	return nil
+}
+
+// declaredSizeAchievable tells whether the uncompressed size can be produced from the compressed size,
+// methods registered outside of archive/zip are not checked
+func declaredSizeAchievable(file *zip.File) bool {
+	switch file.Method {
+	case zip.Store:
+		return file.UncompressedSize64 <= file.CompressedSize64
+	case zip.Deflate:
+		return file.UncompressedSize64/maxDeflateRatio <= file.CompressedSize64
+	}
+	return true
+}
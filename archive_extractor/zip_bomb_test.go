@@ -0,0 +1,156 @@
+//go:build tests_group_all
+
+package archive_extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rawZipEntry struct {
+	name         string
+	offset       uint32
+	method       uint16
+	compressed   uint32
+	uncompressed uint32
+	// extra is the extra field of the central directory header only
+	extra []byte
+}
+
+// localHeader returns a local file header followed by content, stored without compression
+func localHeader(name string, content []byte) []byte {
+	header := make([]byte, localFileHeaderLen)
+	binary.LittleEndian.PutUint32(header, 0x04034b50)
+	binary.LittleEndian.PutUint32(header[18:], uint32(len(content)))
+	binary.LittleEndian.PutUint32(header[22:], uint32(len(content)))
+	binary.LittleEndian.PutUint16(header[26:], uint16(len(name)))
+	return append(append(header, name...), content...)
+}
+
+// rawZip appends a central directory describing entries to data, so entries can point anywhere in data
+func rawZip(data []byte, entries ...rawZipEntry) []byte {
+	buf := bytes.NewBuffer(append([]byte{}, data...))
+	for _, entry := range entries {
+		header := make([]byte, centralDirectoryHeaderLen)
+		binary.LittleEndian.PutUint32(header, 0x02014b50)
+		binary.LittleEndian.PutUint16(header[4:], 20)
+		binary.LittleEndian.PutUint16(header[6:], 20)
+		binary.LittleEndian.PutUint16(header[10:], entry.method)
+		binary.LittleEndian.PutUint32(header[20:], entry.compressed)
+		binary.LittleEndian.PutUint32(header[24:], entry.uncompressed)
+		binary.LittleEndian.PutUint16(header[28:], uint16(len(entry.name)))
+		binary.LittleEndian.PutUint16(header[30:], uint16(len(entry.extra)))
+		binary.LittleEndian.PutUint32(header[42:], entry.offset)
+		buf.Write(header)
+		buf.WriteString(entry.name)
+		buf.Write(entry.extra)
+	}
+	end := make([]byte, 22)
+	binary.LittleEndian.PutUint32(end, 0x06054b50)
+	binary.LittleEndian.PutUint16(end[8:], uint16(len(entries)))
+	binary.LittleEndian.PutUint16(end[10:], uint16(len(entries)))
+	binary.LittleEndian.PutUint32(end[12:], uint32(buf.Len()-len(data)))
+	binary.LittleEndian.PutUint32(end[16:], uint32(len(data)))
+	buf.Write(end)
+	return buf.Bytes()
+}
+
+func assertZipBomb(t *testing.T, data []byte, name string, reason ZipBombReason) {
+	za := ZipArchiver{}
+	source := NewReaderAtSource("bomb.zip", bytes.NewReader(data), int64(len(data)))
+	err := za.ExtractSource(context.Background(), source, func(header *ArchiveHeader, params map[string]interface{}) error {
+		t.Fatalf("%s should not be processed", header.Name)
+		return nil
+	}, params())
+	var errZipBomb *ErrZipBomb
+	require.ErrorAs(t, err, &errZipBomb)
+	assert.Equal(t, ErrZipBomb{Name: name, Reason: reason}, *errZipBomb)
+}
+
+func TestZipBombSharedData(t *testing.T) {
+	content := make([]byte, 200)
+	assertZipBomb(t, rawZip(localHeader("a", content),
+		rawZipEntry{name: "a", compressed: 200, uncompressed: 200},
+		rawZipEntry{name: "b", compressed: 200, uncompressed: 200},
+	), "b", ZipBombSharedData)
+}
+
+func TestZipBombOverlappingEntries(t *testing.T) {
+	a := localHeader("a", make([]byte, 100))
+	b := localHeader("b", make([]byte, 100))
+	assertZipBomb(t, rawZip(append(a, b...),
+		rawZipEntry{name: "a", compressed: 150, uncompressed: 150},
+		rawZipEntry{name: "b", offset: uint32(len(a)), compressed: 100, uncompressed: 100},
+	), "b", ZipBombOverlappingEntries)
+}
+
+func TestZipBombDeclaredSize(t *testing.T) {
+	assertZipBomb(t, rawZip(localHeader("a", make([]byte, 10)),
+		rawZipEntry{name: "a", method: zip.Deflate, compressed: 10, uncompressed: 100 * 1024 * 1024},
+	), "a", ZipBombDeclaredSize)
+	assertZipBomb(t, rawZip(localHeader("a", make([]byte, 10)),
+		rawZipEntry{name: "a", compressed: 1 << 20, uncompressed: 1 << 20},
+	), "a", ZipBombDeclaredSize)
+}
+
+func TestZipBombTooManyEntries(t *testing.T) {
+	entries := make([]rawZipEntry, 20)
+	for i := range entries {
+		entries[i] = rawZipEntry{name: "a"}
+	}
+	assertZipBomb(t, rawZip(localHeader("a", nil), entries...), "", ZipBombTooManyEntries)
+}
+
+func TestZipBombNotDetected(t *testing.T) {
+	a := localHeader("a", []byte("content a"))
+	b := localHeader("b", []byte("content b"))
+	data := rawZip(append(a, b...),
+		rawZipEntry{name: "a", compressed: 9, uncompressed: 9},
+		rawZipEntry{name: "b", offset: uint32(len(a)), compressed: 9, uncompressed: 9},
+	)
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.NoError(t, checkZipBomb(r, int64(len(data))))
+	for _, path := range []string{"./fixtures/testwithcontent.zip", "./fixtures/testwithmanyfiles.zip", "./fixtures/appendedZip"} {
+		assert.NoError(t, ZipArchiver{}.ExtractArchive(path, processingReadingFunc, params()), path)
+	}
+}
+
+func TestZipBombNotDetectedWithLongCentralExtras(t *testing.T) {
+	// a field of an unknown kind, as Info-ZIP writes to the central directory only
+	extra := make([]byte, 64)
+	binary.LittleEndian.PutUint16(extra, 0xcafe)
+	binary.LittleEndian.PutUint16(extra[2:], uint16(len(extra)-4))
+	var data []byte
+	var entries []rawZipEntry
+	for i := range 50 {
+		name := fmt.Sprintf("e%02d", i)
+		entries = append(entries, rawZipEntry{name: name, offset: uint32(len(data)), extra: extra})
+		data = append(data, localHeader(name, nil)...)
+	}
+	data = rawZip(data, entries...)
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Len(t, r.File, 50)
+	assert.NoError(t, checkZipBomb(r, int64(len(data))))
+}
+
+func TestArchiveFSZipBomb(t *testing.T) {
+	path := t.TempDir() + "/bomb.zip"
+	content := make([]byte, 200)
+	data := rawZip(localHeader("a", content),
+		rawZipEntry{name: "a", compressed: 200, uncompressed: 200},
+		rawZipEntry{name: "b", compressed: 200, uncompressed: 200},
+	)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	_, err := OpenFS(path)
+	assert.True(t, IsErrZipBomb(err))
+}
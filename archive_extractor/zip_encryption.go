@@ -0,0 +1,284 @@
+package archive_extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	zipFlagEncrypted       = 0x1
+	zipFlagDataDescriptor  = 0x8
+	zipFlagStrongEncrypted = 0x40
+	// zipMethodAES is the method of the WinZip AES encrypted entries, their actual method is stored in their extra field
+	zipMethodAES         = 99
+	winZipAESExtraID     = 0x9901
+	winZipAESIterations  = 1000
+	winZipAESVerifierLen = 2
+	winZipAESAuthCodeLen = 10
+	zipCryptoHeaderLen   = 12
+	zipCryptoKey0        = 0x12345678
+	zipCryptoKey1        = 0x23456789
+	zipCryptoKey2        = 0x34567890
+)
+
+// openZipEntry opens the content of file, decrypting it with the password of the archive when it is encrypted.
+// The encrypted entries that can't be decrypted are opened as a reader returning ErrEncrypted or ErrWrongPassword,
+// so they are still passed to the processing function.
+func openZipEntry(file *zip.File, password *archivePassword) (io.ReadCloser, error) {
+	if file.Flags&zipFlagEncrypted == 0 {
+		return file.Open()
+	}
+	if file.Flags&zipFlagStrongEncrypted != 0 {
+		return nil, zip.ErrAlgorithm
+	}
+	pw, err := password.get()
+	if err != nil {
+		return nil, err
+	}
+	if pw == "" {
+		return errorReader{err: ErrEncrypted}, nil
+	}
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	method := file.Method
+	checkCRC := true
+	var content io.Reader
+	if file.Method == zipMethodAES {
+		var aesExtra winZipAESExtra
+		if aesExtra, err = parseWinZipAESExtra(file.Extra); err != nil {
+			return nil, err
+		}
+		method = aesExtra.method
+		// AE-2 doesn't store the CRC, the authentication code is checked instead
+		checkCRC = aesExtra.version == 1
+		content, err = newWinZipAESReader(raw, file.CompressedSize64, aesExtra.strength, pw)
+	} else {
+		content, err = newZipCryptoReader(raw, zipCryptoCheckByte(file), pw)
+	}
+	if err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			return errorReader{err: err}, nil
+		}
+		return nil, err
+	}
+	var rc io.ReadCloser
+	switch method {
+	case zip.Store:
+		rc = io.NopCloser(content)
+	case zip.Deflate:
+		rc = flate.NewReader(content)
+	default:
+		return nil, zip.ErrAlgorithm
+	}
+	if checkCRC {
+		rc = &crcReadCloser{ReadCloser: rc, hash: crc32.NewIEEE(), expected: file.CRC32}
+	}
+	if file.Method == zipMethodAES {
+		rc = &drainingReadCloser{ReadCloser: rc, rest: content}
+	}
+	return rc, nil
+}
+
+// drainingReadCloser reads what remains of rest once the content ends, so the authentication code following it is checked
+type drainingReadCloser struct {
+	io.ReadCloser
+	rest io.Reader
+}
+
+func (drc *drainingReadCloser) Read(p []byte) (int, error) {
+	n, err := drc.ReadCloser.Read(p)
+	if err == io.EOF {
+		if _, drainErr := io.Copy(io.Discard, drc.rest); drainErr != nil {
+			return n, drainErr
+		}
+	}
+	return n, err
+}
+
+// crcReadCloser checks the CRC of the decrypted entries, which archive/zip can't read
+type crcReadCloser struct {
+	io.ReadCloser
+	hash     hash.Hash32
+	expected uint32
+}
+
+func (crc *crcReadCloser) Read(p []byte) (int, error) {
+	n, err := crc.ReadCloser.Read(p)
+	crc.hash.Write(p[:n])
+	if err == io.EOF && crc.hash.Sum32() != crc.expected {
+		err = zip.ErrChecksum
+	}
+	return n, err
+}
+
+// zipCryptoCheckByte is the byte the decrypted encryption header ends with, the password is wrong if it doesn't match
+func zipCryptoCheckByte(file *zip.File) byte {
+	if file.Flags&zipFlagDataDescriptor != 0 {
+		// the CRC isn't known when the header is written, the modification time is used instead
+		return byte(file.ModifiedTime >> 8)
+	}
+	return byte(file.CRC32 >> 24)
+}
+
+// zipCryptoReader decrypts the traditional PKWARE encryption (ZipCrypto)
+type zipCryptoReader struct {
+	reader io.Reader
+	keys   [3]uint32
+}
+
+func newZipCryptoReader(raw io.Reader, checkByte byte, password string) (*zipCryptoReader, error) {
+	zr := &zipCryptoReader{reader: raw, keys: [3]uint32{zipCryptoKey0, zipCryptoKey1, zipCryptoKey2}}
+	for i := 0; i < len(password); i++ {
+		zr.update(password[i])
+	}
+	header := make([]byte, zipCryptoHeaderLen)
+	if _, err := io.ReadFull(zr, header); err != nil {
+		return nil, err
+	}
+	if header[zipCryptoHeaderLen-1] != checkByte {
+		return nil, ErrWrongPassword
+	}
+	return zr, nil
+}
+
+func (zr *zipCryptoReader) update(b byte) {
+	zr.keys[0] = crc32.IEEETable[byte(zr.keys[0])^b] ^ zr.keys[0]>>8
+	zr.keys[1] = (zr.keys[1]+zr.keys[0]&0xff)*134775813 + 1
+	zr.keys[2] = crc32.IEEETable[byte(zr.keys[2])^byte(zr.keys[1]>>24)] ^ zr.keys[2]>>8
+}
+
+func (zr *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := zr.reader.Read(p)
+	for i := 0; i < n; i++ {
+		temp := zr.keys[2] | 2
+		p[i] ^= byte(temp * (temp ^ 1) >> 8)
+		zr.update(p[i])
+	}
+	return n, err
+}
+
+type winZipAESExtra struct {
+	version  uint16
+	strength byte
+	method   uint16
+}
+
+func parseWinZipAESExtra(extra []byte) (winZipAESExtra, error) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if data := extra[4 : 4+size]; id == winZipAESExtraID && size >= 7 {
+			return winZipAESExtra{
+				version:  binary.LittleEndian.Uint16(data),
+				strength: data[4],
+				method:   binary.LittleEndian.Uint16(data[5:]),
+			}, nil
+		}
+		extra = extra[4+size:]
+	}
+	return winZipAESExtra{}, zip.ErrFormat
+}
+
+// newWinZipAESReader decrypts the WinZip AES encryption: the salt and the password verifier are followed by
+// the content encrypted with AES in counter mode, and by the HMAC-SHA1 authentication code of the encrypted content
+func newWinZipAESReader(raw io.Reader, compressedSize uint64, strength byte, password string) (io.Reader, error) {
+	if strength < 1 || strength > 3 {
+		return nil, zip.ErrAlgorithm
+	}
+	keyLen := 8 + 8*int(strength)
+	saltLen := keyLen / 2
+	overhead := uint64(saltLen + winZipAESVerifierLen + winZipAESAuthCodeLen)
+	if compressedSize < overhead {
+		return nil, zip.ErrFormat
+	}
+	header := make([]byte, saltLen+winZipAESVerifierLen)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, err
+	}
+	keys, err := pbkdf2.Key(sha1.New, password, header[:saltLen], winZipAESIterations, 2*keyLen+winZipAESVerifierLen)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(keys[2*keyLen:], header[saltLen:]) {
+		return nil, ErrWrongPassword
+	}
+	block, err := aes.NewCipher(keys[:keyLen])
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, keys[keyLen:2*keyLen])
+	return &winZipAESReader{
+		raw:       raw,
+		encrypted: io.LimitReader(raw, int64(compressedSize-overhead)),
+		stream:    &littleEndianCTR{block: block, counter: make([]byte, aes.BlockSize), keyStream: make([]byte, aes.BlockSize), used: aes.BlockSize},
+		mac:       mac,
+	}, nil
+}
+
+type winZipAESReader struct {
+	raw       io.Reader
+	encrypted io.Reader
+	stream    cipher.Stream
+	mac       hash.Hash
+	// authenticated is set once the authentication code following the content is checked
+	authenticated bool
+}
+
+func (ar *winZipAESReader) Read(p []byte) (int, error) {
+	n, err := ar.encrypted.Read(p)
+	ar.mac.Write(p[:n])
+	ar.stream.XORKeyStream(p[:n], p[:n])
+	if err == io.EOF && !ar.authenticated {
+		ar.authenticated = true
+		authCode := make([]byte, winZipAESAuthCodeLen)
+		if _, readErr := io.ReadFull(ar.raw, authCode); readErr != nil {
+			return n, readErr
+		}
+		if !hmac.Equal(authCode, ar.mac.Sum(nil)[:winZipAESAuthCodeLen]) {
+			return n, zip.ErrChecksum
+		}
+	}
+	return n, err
+}
+
+// littleEndianCTR is the counter mode of WinZip AES, its counter starts at 1 and is incremented as a little endian number,
+// while cipher.NewCTR increments a big endian one
+type littleEndianCTR struct {
+	block     cipher.Block
+	counter   []byte
+	keyStream []byte
+	used      int
+}
+
+func (ctr *littleEndianCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if ctr.used == len(ctr.keyStream) {
+			for j := range ctr.counter {
+				ctr.counter[j]++
+				if ctr.counter[j] != 0 {
+					break
+				}
+			}
+			ctr.block.Encrypt(ctr.keyStream, ctr.counter)
+			ctr.used = 0
+		}
+		dst[i] = src[i] ^ ctr.keyStream[ctr.used]
+		ctr.used++
+	}
+}
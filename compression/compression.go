@@ -8,11 +8,13 @@ import (
 	"compress/gzip"
 	"compress/lzw"
 	"compress/zlib"
+	"context"
 	"errors"
 	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/jfrog/go-archive-extractor/utils"
 	"github.com/klauspost/compress/zstd"
 	"github.com/mholt/archives"
 	"github.com/ulikunitz/xz"
@@ -46,11 +48,40 @@ var (
 	lzipMagic = []byte{0x4C, 0x5A, 0x49, 0x50} // "LZIP"
 )
 
+// Format is the name of a supported compression
+type Format string
+
+const (
+	None  Format = ""
+	Bzip2 Format = "bzip2"
+	Gzip  Format = "gzip"
+	Lzw   Format = "lzw"
+	Flate Format = "flate"
+	Zlib  Format = "zlib"
+	Xz    Format = "xz"
+	Lzma  Format = "lzma"
+	Zstd  Format = "zstd"
+	Lzip  Format = "lzip"
+)
+
+var formatReaders = map[Format]func(io.Reader) (io.ReadCloser, error){
+	Bzip2: bz2Reader,
+	Gzip:  gzipReader,
+	Lzw:   lzwReader,
+	Flate: flateReader,
+	Zlib:  zlibReader,
+	Xz:    xzReader,
+	Lzma:  lzmaReader,
+	Zstd:  zstdReader,
+	Lzip:  lzipReader,
+}
+
 const defaultBufSize = 32 * 1024
 
 type readerConfiguration struct {
 	BufSize   int
 	SkipBytes int64
+	Ctx       context.Context
 }
 
 type Option func(*readerConfiguration)
@@ -67,12 +98,46 @@ func WithBufSize(size int) Option {
 	}
 }
 
+// WithContext makes the returned reader fail with ctx.Err() once ctx is done
+func WithContext(ctx context.Context) Option {
+	return func(c *readerConfiguration) {
+		c.Ctx = ctx
+	}
+}
+
 func NewReader(filePath string, options ...Option) (io.ReadCloser, bool, error) {
-	config := &readerConfiguration{BufSize: defaultBufSize, SkipBytes: 0}
+	config := newReaderConfiguration(options)
+	f, err := (&fileArgs{path: filePath, skipBytes: config.SkipBytes}).open()
+	if err != nil {
+		return nil, false, err
+	}
+	reader, isCompressed, err := newReader(f, filepath.Ext(filePath), f, config)
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	return reader, isCompressed, nil
+}
+
+// NewReaderFrom works like NewReader on a stream that is not stored in a file.
+// name is only used to detect the compressions that have no magic bytes by their extension.
+// Closing the returned reader does not close the given reader.
+func NewReaderFrom(reader io.Reader, name string, options ...Option) (io.ReadCloser, bool, error) {
+	config := newReaderConfiguration(options)
+	if config.SkipBytes > 0 {
+		if _, err := io.CopyN(io.Discard, reader, config.SkipBytes); err != nil {
+			return nil, false, err
+		}
+	}
+	return newReader(reader, filepath.Ext(name), io.NopCloser(reader), config)
+}
+
+func newReaderConfiguration(options []Option) *readerConfiguration {
+	config := &readerConfiguration{BufSize: defaultBufSize, SkipBytes: 0, Ctx: context.Background()}
 	for _, option := range options {
 		option(config)
 	}
-	return newReader(&fileArgs{path: filePath, skipBytes: config.SkipBytes}, config)
+	return config
 }
 
 type fileArgs struct {
@@ -95,91 +160,90 @@ func (fa *fileArgs) open() (*os.File, error) {
 	return f, nil
 }
 
-func newReader(fa *fileArgs, conf *readerConfiguration) (reader io.ReadCloser, isCompressed bool, err error) {
-	isCompressed = true
-	ext := filepath.Ext(fa.path)
+func newReader(source io.Reader, ext string, closer io.Closer, conf *readerConfiguration) (reader io.ReadCloser, isCompressed bool, err error) {
+	bufReader := bufio.NewReaderSize(utils.NewContextReader(conf.Ctx, source), conf.BufSize)
+	magic, _ := bufReader.Peek(maxMagicBytes)
+	format := detectFormat(ext, magic)
+	getReader, isCompressed := formatReaders[format]
+	if !isCompressed {
+		getReader = fileReader
+	}
+	reader, err = initReader(bufReader, closer, getReader)
+	return
+}
+
+// DetectFormat returns the compression of a stream named name which starts with magic,
+// or None if the stream is not compressed or the compression is not supported.
+// The extension of name is used for the compressions without magic bytes, and when magic is empty.
+func DetectFormat(name string, magic []byte) Format {
+	return detectFormat(filepath.Ext(name), magic)
+}
+
+func detectFormat(ext string, magic []byte) Format {
+	// if possible detect by magic bytes, so that misnamed files are still read
+	switch {
+	case len(magic) == 0:
+	case bytes.HasPrefix(magic, bz2Magic):
+		return Bzip2
+	case bytes.HasPrefix(magic, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(magic, xzMagic):
+		return Xz
+	case bytes.HasPrefix(magic, lzmaMagic):
+		return Lzma
+	case bytes.HasPrefix(magic, lzipMagic):
+		return Lzip
+	case bytes.HasPrefix(magic, zstdMagic):
+		return Zstd
+	}
 	//these types has no defined magic bytes
 	switch ext {
 	case lzwExt:
-		reader, err = initReader(fa, conf, lzwReader)
-		return
+		return Lzw
 	case inflExt:
-		reader, err = initReader(fa, conf, flateReader)
-		return
+		return Flate
 	case zlibExt:
-		reader, err = initReader(fa, conf, zlibReader)
-		return
+		return Zlib
 	case lzipExt:
-		reader, err = initReader(fa, conf, lzipReader)
-		return
+		return Lzip
+	case lzmaExt, tlzmaExt:
+		// the lzma magic bytes only match the default properties
+		return Lzma
 	}
-	// if possible init by magic bytes
-	if magic, magicErr := getMagicBytes(fa); magicErr == nil {
-		switch {
-		case bytes.HasPrefix(magic, bz2Magic):
-			reader, err = initReader(fa, conf, bz2Reader)
-			return
-		case bytes.HasPrefix(magic, gzipMagic):
-			reader, err = initReader(fa, conf, gzipReader)
-			return
-		case bytes.HasPrefix(magic, xzMagic):
-			reader, err = initReader(fa, conf, xzReader)
-			return
-		case bytes.HasPrefix(magic, lzmaMagic):
-			reader, err = initReader(fa, conf, lzmaReader)
-			return
-		case bytes.HasPrefix(magic, lzipMagic):
-			reader, err = initReader(fa, conf, lzipReader)
-			return
-		case bytes.HasPrefix(magic, zstdMagic):
-			reader, err = initReader(fa, conf, zstdReader)
-			return
-		}
+	if len(magic) > 0 {
+		// the other formats always start with their magic bytes, the content is not compressed whatever its extension
+		return None
 	}
-	// fallback to init by extension
+	// fallback to detect by extension
 	switch ext {
 	case bz2Ext, tbz2Ext:
-		reader, err = initReader(fa, conf, bz2Reader)
-		return
+		return Bzip2
 	case gzExt, tgzExt:
-		reader, err = initReader(fa, conf, gzipReader)
-		return
+		return Gzip
 	case xzExt, txzExt:
-		reader, err = initReader(fa, conf, xzReader)
-		return
-	case lzmaExt, tlzmaExt:
-		reader, err = initReader(fa, conf, lzmaReader)
-		return
+		return Xz
 	case zstdExt:
-		reader, err = initReader(fa, conf, zstdReader)
-		return
+		return Zstd
 	default:
 		// no compression format found
-		isCompressed = false
-		reader, err = initReader(fa, conf, fileReader)
-		return
+		return None
 	}
 }
 
-func getMagicBytes(fa *fileArgs) ([]byte, error) {
-	f, err := fa.open()
-	if err != nil {
-		return nil, err
+// HasMagicBytes reports whether format is detected by its content rather than only by the file extension
+func HasMagicBytes(format Format) bool {
+	switch format {
+	case Bzip2, Gzip, Xz, Lzma, Lzip, Zstd:
+		return true
 	}
-	defer f.Close()
-
-	b := make([]byte, maxMagicBytes)
-	if _, err = f.Read(b); err != nil {
-		return nil, err
-	}
-	return b, nil
+	return false
 }
 
 // compression readers
 
 type cReader struct {
 	reader io.ReadCloser
-	file   *os.File
+	closer io.Closer
 }
 
 func (cr *cReader) Read(p []byte) (int, error) {
@@ -187,7 +251,7 @@ func (cr *cReader) Read(p []byte) (int, error) {
 }
 
 func (cr *cReader) Close() error {
-	if err := cr.file.Close(); err != nil {
+	if err := cr.closer.Close(); err != nil {
 		return err
 	}
 	if err := cr.reader.Close(); err != nil {
@@ -196,19 +260,13 @@ func (cr *cReader) Close() error {
 	return nil
 }
 
-func initReader(fa *fileArgs, conf *readerConfiguration, getReader func(io.Reader) (io.ReadCloser, error)) (io.ReadCloser, error) {
-
-	f, err := fa.open()
-	if err != nil {
-		return nil, err
-	}
-	r, err := getReader(bufio.NewReaderSize(f, conf.BufSize))
+func initReader(bufReader *bufio.Reader, closer io.Closer, getReader func(io.Reader) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	r, err := getReader(bufReader)
 	if err != nil {
-		f.Close()
 		return nil, &ErrGetReader{err}
 	}
 
-	return &cReader{reader: r, file: f}, nil
+	return &cReader{reader: r, closer: closer}, nil
 }
 
 type ErrGetReader struct {
@@ -219,6 +277,10 @@ func (e *ErrGetReader) Error() string {
 	return e.err.Error()
 }
 
+func (e *ErrGetReader) Unwrap() error {
+	return e.err
+}
+
 func IsGetReaderError(err error) bool {
 	for e := err; e != nil; e = errors.Unwrap(e) {
 		if _, ok := e.(*ErrGetReader); ok {
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"context"
+	"io"
+)
+
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// NewContextReader returns a reader that fails with ctx.Err() as soon as ctx is done.
+// Close is forwarded to the wrapped reader when it implements io.Closer.
+func NewContextReader(ctx context.Context, reader io.Reader) io.ReadCloser {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.reader.Read(p)
+}
+
+func (cr *contextReader) Close() error {
+	closer, ok := cr.reader.(io.Closer)
+	if ok {
+		return closer.Close()
+	}
+	return nil
+}